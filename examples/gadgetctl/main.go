@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"go.pdmccormick.com/linuxuapi/usb/usbgadget/gadgetconfig"
+)
+
+// Definitions applied by `up`, so `down` can find them by gadget name. Like
+// configfs itself, this does not survive a reboot.
+const DefaultStateDir = "/run/gadgetctl"
+
+var commands = map[string]func(args []string){
+	"up":     cmdUp,
+	"down":   cmdDown,
+	"apply":  cmdApply,
+	"ls":     cmdLs,
+	"show":   cmdShow,
+	"udc":    cmdUdc,
+	"tty":    cmdTty,
+	"media":  cmdMedia,
+	"script": cmdScript,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: gadgetctl <command> [flags] [args]
+
+  up <file>            create a gadget from a JSON definition and bind it
+  down <name|file>     unbind and remove a gadget
+  apply [-n] <file>    change a gadget to match a definition, in place
+  ls                   list gadgets with their UDC and state
+  show [-raw] <name>   show a gadget as loaded from configfs
+  udc                  list device controllers
+  tty [-attach fn] <name>
+                       list serial function ttys, or relay stdio to one
+  media [-lun n] [-eject] [-ro|-rw] <name> <function> [file]
+                       change the media of a mass storage LUN
+  script [-rm] <file>  print the equivalent shell commands
+`)
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gadgetctl: ")
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+
+	cmd(os.Args[2:])
+}
+
+func stateFile(dir, name string) string { return filepath.Join(dir, name+".json") }
+
+// Accept a definition file, or the name of a gadget. Gadgets brought up
+// earlier are described by the definition that was applied, and any others
+// by their live configfs state.
+func resolveGadget(arg, stateDir string) *gadgetconfig.Gadget {
+	var path = arg
+
+	if _, err := os.Stat(path); err != nil && !strings.ContainsRune(arg, '/') {
+		path = stateFile(stateDir, arg)
+
+		if _, err := os.Stat(path); err != nil {
+			g, err := gadgetconfig.Load(arg)
+			if err != nil {
+				log.Fatalf("gadget `%s`: %s", arg, err)
+			}
+
+			return g
+		}
+	}
+
+	g, err := gadgetconfig.ReadGadgetFile(path)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	return g
+}
+
+func cmdUp(args []string) {
+	var (
+		fs       = flag.NewFlagSet("up", flag.ExitOnError)
+		udcFlag  = fs.String("udc", "", "bind to `udc`, overriding the definition, default the first found")
+		stateDir = fs.String("state", DefaultStateDir, "`dir`ectory recording applied definitions")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	g, err := gadgetconfig.ReadGadgetFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if *udcFlag != "" {
+		g.UDC = *udcFlag
+	}
+
+	if g.UDC == "" {
+		udcs := gadgetconfig.FindUdc()
+		if len(udcs) == 0 {
+			log.Fatalf("no UDC found")
+		}
+
+		g.UDC = udcs[0]
+	}
+
+	if g.Exists() {
+		log.Fatalf("gadget `%s` already exists", g.Name)
+	}
+
+	if err := g.Create(); err != nil {
+		log.Fatalf("create `%s`: %s", g.Name, err)
+	}
+
+	if err := os.MkdirAll(*stateDir, 0o755); err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if err := gadgetconfig.WriteGadgetFile(stateFile(*stateDir, g.Name), g); err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	log.Printf("gadget `%s` bound to %s", g.Name, g.UDC)
+}
+
+func cmdDown(args []string) {
+	var (
+		fs       = flag.NewFlagSet("down", flag.ExitOnError)
+		stateDir = fs.String("state", DefaultStateDir, "`dir`ectory recording applied definitions")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	var g = resolveGadget(fs.Arg(0), *stateDir)
+
+	if !g.Exists() {
+		log.Fatalf("gadget `%s` does not exist", g.Name)
+	}
+
+	if err := g.Remove(); err != nil {
+		log.Fatalf("remove `%s`: %s", g.Name, err)
+	}
+
+	os.Remove(stateFile(*stateDir, g.Name))
+
+	log.Printf("gadget `%s` removed", g.Name)
+}
+
+func cmdApply(args []string) {
+	var (
+		fs       = flag.NewFlagSet("apply", flag.ExitOnError)
+		dryFlag  = fs.Bool("n", false, "print the plan without applying it")
+		stateDir = fs.String("state", DefaultStateDir, "`dir`ectory recording applied definitions")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	g, err := gadgetconfig.ReadGadgetFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	steps, err := g.ReconcileSteps()
+	if err != nil {
+		log.Fatalf("plan `%s`: %s", g.Name, err)
+	}
+
+	if *dryFlag {
+		steps.ShellArgs().Dump(os.Stdout)
+		return
+	}
+
+	// Rolled back on failure, rather than leaving it half applied and unbound
+	if err := steps.RunTransaction(); err != nil {
+		log.Fatalf("apply `%s`: %s", g.Name, err)
+	}
+
+	if err := os.MkdirAll(*stateDir, 0o755); err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if err := gadgetconfig.WriteGadgetFile(stateFile(*stateDir, g.Name), g); err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	log.Printf("gadget `%s` applied, %d steps", g.Name, len(steps))
+}
+
+func cmdLs(args []string) {
+	var fs = flag.NewFlagSet("ls", flag.ExitOnError)
+	fs.Parse(args)
+
+	var w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "NAME\tUDC\tSTATE\n")
+
+	for _, name := range gadgetconfig.ListGadgets() {
+		var (
+			g     = gadgetconfig.Gadget{Name: name}
+			udc   = g.BoundUdc()
+			state = "-"
+		)
+
+		if udc == "" {
+			udc = "-"
+		} else if info, err := gadgetconfig.ReadUdc(udc); err == nil {
+			state = info.State
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, udc, state)
+	}
+}
+
+func cmdShow(args []string) {
+	var (
+		fs      = flag.NewFlagSet("show", flag.ExitOnError)
+		rawFlag = fs.Bool("raw", false, "list every configfs attribute rather than the decoded gadget")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	if *rawFlag {
+		showRaw(fs.Arg(0))
+		return
+	}
+
+	g, err := gadgetconfig.Load(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("gadget `%s`: %s", fs.Arg(0), err)
+	}
+
+	data, err := json.MarshalIndent(g, "", "\t")
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	fmt.Printf("%s\n", data)
+}
+
+func showRaw(name string) {
+	var g = gadgetconfig.Gadget{Name: name}
+
+	if !g.Exists() {
+		log.Fatalf("gadget `%s` does not exist", g.Name)
+	}
+
+	var root = filepath.Join(gadgetconfig.GadgetConfigBasePath, g.Name)
+
+	filepath.WalkDir(root, func(path string, ent os.DirEntry, err error) error {
+		if err != nil || ent.IsDir() {
+			return nil
+		}
+
+		var rel, _ = filepath.Rel(root, path)
+
+		if ent.Type()&os.ModeSymlink != 0 {
+			target, _ := os.Readlink(path)
+			fmt.Printf("%s -> %s\n", rel, filepath.Base(target))
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// Some attributes are write only
+			return nil
+		}
+
+		fmt.Printf("%s = %s\n", rel, showValue(data))
+		return nil
+	})
+}
+
+func showValue(data []byte) string {
+	var s = strings.TrimRight(string(data), "\n")
+
+	if !utf8.ValidString(s) || strings.ContainsFunc(s, func(r rune) bool { return r < ' ' && r != '\n' }) {
+		return fmt.Sprintf("[%d bytes] % x", len(data), data)
+	}
+
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func cmdUdc(args []string) {
+	var fs = flag.NewFlagSet("udc", flag.ExitOnError)
+	fs.Parse(args)
+
+	var bound = make(map[string]string)
+	for _, name := range gadgetconfig.ListGadgets() {
+		var g = gadgetconfig.Gadget{Name: name}
+		if udc := g.BoundUdc(); udc != "" {
+			bound[udc] = name
+		}
+	}
+
+	var udcs = gadgetconfig.FindUdc()
+	sort.Strings(udcs)
+
+	var w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "UDC\tSTATE\tSPEED\tMAX SPEED\tGADGET\n")
+
+	for _, name := range udcs {
+		info, err := gadgetconfig.ReadUdc(name)
+		if err != nil {
+			log.Printf("%s", err)
+			continue
+		}
+
+		var gadget = bound[name]
+		if gadget == "" {
+			gadget = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, info.State, info.CurrentSpeed, info.MaximumSpeed, gadget)
+	}
+}
+
+func cmdScript(args []string) {
+	var (
+		fs     = flag.NewFlagSet("script", flag.ExitOnError)
+		rmFlag = fs.Bool("rm", false, "emit removal rather than creation steps")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	g, err := gadgetconfig.ReadGadgetFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if *rmFlag {
+		g.ShellRemove().Dump(os.Stdout)
+	} else {
+		g.ShellCreate().Dump(os.Stdout)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"go.pdmccormick.com/linuxuapi/usb/usbgadget/gadgetconfig"
+)
+
+func cmdMedia(args []string) {
+	var (
+		fs        = flag.NewFlagSet("media", flag.ExitOnError)
+		ejectFlag = fs.Bool("eject", false, "remove the media")
+		roFlag    = fs.Bool("ro", false, "make the LUN read-only")
+		rwFlag    = fs.Bool("rw", false, "make the LUN writable")
+		lunFlag   = fs.String("lun", "0", "LUN `name`")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() < 2 || fs.NArg() > 3 || (*roFlag && *rwFlag) {
+		usage()
+	}
+
+	g, err := gadgetconfig.Load(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("gadget `%s`: %s", fs.Arg(0), err)
+	}
+
+	var fn = findMassStorage(g, fs.Arg(1))
+	if fn == nil {
+		log.Fatalf("gadget `%s` has no mass storage function `%s`", g.Name, fs.Arg(1))
+	}
+
+	var lun = *lunFlag
+
+	switch {
+	case *ejectFlag:
+		err = fn.Eject(g, lun)
+
+	case fs.NArg() == 3:
+		err = fn.SetFile(g, lun, fs.Arg(2))
+	}
+
+	if err == nil && (*roFlag || *rwFlag) {
+		err = fn.SetReadOnly(g, lun, *roFlag)
+	}
+
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	file, err := fn.File(g, lun)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if file == "" {
+		file = "(no media)"
+	}
+
+	fmt.Printf("%s lun %s: %s\n", fn.GadgetFunctionName(), lun, file)
+}
+
+// Accepts the function name with or without its `mass_storage.` type
+func findMassStorage(g *gadgetconfig.Gadget, name string) *gadgetconfig.MassStorageFunction {
+	for _, c := range g.Configs {
+		for _, fn := range c.Functions {
+			if ms, ok := fn.(*gadgetconfig.MassStorageFunction); ok && (ms.Name == name || ms.GadgetFunctionName() == name) {
+				return ms
+			}
+		}
+	}
+
+	return nil
+}
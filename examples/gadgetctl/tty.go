@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"go.pdmccormick.com/linuxuapi/usb/usbgadget/gadgetconfig"
+)
+
+func cmdTty(args []string) {
+	var (
+		fs         = flag.NewFlagSet("tty", flag.ExitOnError)
+		attachFlag = fs.String("attach", "", "relay stdin and stdout to the tty of this `function`")
+	)
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	g, err := gadgetconfig.Load(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("gadget `%s`: %s", fs.Arg(0), err)
+	}
+
+	var ports = serialPorts(g)
+
+	if *attachFlag != "" {
+		port, ok := ports[*attachFlag]
+		if !ok {
+			log.Fatalf("gadget `%s` has no serial function `%s`", g.Name, *attachFlag)
+		}
+
+		attach(g, port)
+		return
+	}
+
+	var w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "FUNCTION\tTTY\n")
+
+	var names = make([]string, 0, len(ports))
+	for name := range ports {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		path, err := gadgetconfig.TtyPath(g, ports[name])
+		if err != nil {
+			path = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", name, path)
+	}
+}
+
+// Serial functions of every config, by function name
+func serialPorts(g *gadgetconfig.Gadget) map[string]gadgetconfig.SerialPort {
+	var ports = make(map[string]gadgetconfig.SerialPort)
+
+	for _, c := range g.Configs {
+		for _, fn := range c.Functions {
+			if port, ok := fn.(gadgetconfig.SerialPort); ok {
+				ports[fn.GadgetFunctionName()] = port
+			}
+		}
+	}
+
+	return ports
+}
+
+func attach(g *gadgetconfig.Gadget, port gadgetconfig.SerialPort) {
+	tty, err := gadgetconfig.OpenTty(g, port)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	defer tty.Close()
+
+	log.Printf("attached to %s", tty.Name())
+
+	go func() {
+		io.Copy(tty, os.Stdin)
+		tty.Close()
+	}()
+
+	io.Copy(os.Stdout, tty)
+}
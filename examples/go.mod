@@ -6,4 +6,4 @@ go 1.22.2
 
 require go.pdmccormick.com/linuxuapi v0.0.0-00010101000000-000000000000
 
-require golang.org/x/sys v0.19.0 // indirect
+require golang.org/x/sys v0.19.0
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"go.pdmccormick.com/linuxuapi/gpio"
+	"go.pdmccormick.com/linuxuapi/gpio/gpiosim"
+	"go.pdmccormick.com/linuxuapi/i2c/bitbang"
+)
+
+func main() {
+	var (
+		chipFlag  = flag.String("chip", "gpiochip0", "GPIO `chip`")
+		sdaFlag   = flag.Int("sda", -1, "SDA line `offset`")
+		sclFlag   = flag.Int("scl", -1, "SCL line `offset`")
+		pullFlag  = flag.Bool("pull-up", false, "enable internal pull-ups")
+		clockFlag = flag.Int("clock", bitbang.DefaultClock, "SCL frequency in `Hz`")
+		addrFlag  = flag.Int("a", -1, "slave `addr`ess")
+		regFlag   = flag.Int("r", -1, "register `addr`ess")
+		writeFlag = flag.Int("w", -1, "write byte to register")
+		simFlag   = flag.Bool("sim", false, "self test against an emulated EEPROM on gpio-sim lines")
+	)
+
+	flag.Parse()
+
+	if *simFlag {
+		selfTest()
+		return
+	}
+
+	if *sdaFlag < 0 || *sclFlag < 0 {
+		log.Fatalf("missing `-sda` or `-scl` flag")
+	}
+
+	if *addrFlag < 0 || *regFlag < 0 {
+		log.Fatalf("missing `-a` or `-r` flag")
+	}
+
+	chip, err := gpio.OpenChip(*chipFlag)
+	if err != nil {
+		log.Fatalf("OpenChip: %s", err)
+	}
+
+	defer chip.Close()
+
+	var flags gpio.LineFlags
+	if *pullFlag {
+		flags |= gpio.FlagBiasPullUp
+	}
+
+	bus, err := bitbang.Open(chip, *sdaFlag, *sclFlag, flags)
+	if err != nil {
+		log.Fatalf("bitbang.Open: %s", err)
+	}
+
+	defer bus.Close()
+
+	bus.SetClock(*clockFlag)
+
+	var (
+		addr = uint16(*addrFlag)
+		reg  = byte(*regFlag)
+	)
+
+	if *writeFlag >= 0 {
+		if err := bus.WriteReg(addr, reg, byte(*writeFlag)); err != nil {
+			log.Fatalf("WriteReg: %s", err)
+		}
+	}
+
+	v, err := bus.ReadReg(addr, reg)
+	if err != nil {
+		log.Fatalf("ReadReg: %s", err)
+	}
+
+	fmt.Printf("0x%02x: 0x%02x\n", reg, v)
+}
+
+const (
+	simAddr  = 0x50
+	simClock = 100
+)
+
+func selfTest() {
+	sim, err := gpiosim.Create("i2cbitbang", 2)
+	if err != nil {
+		log.Fatalf("gpiosim.Create: %s", err)
+	}
+
+	defer sim.Close()
+
+	sda, err := sim.OpenLine(0)
+	if err != nil {
+		log.Fatalf("OpenLine: %s", err)
+	}
+
+	defer sda.Close()
+
+	scl, err := sim.OpenLine(1)
+	if err != nil {
+		log.Fatalf("OpenLine: %s", err)
+	}
+
+	defer scl.Close()
+
+	// Stand in for the bus pull-up resistors
+	sda.SetPull(true)
+	scl.SetPull(true)
+
+	var (
+		t    = target{addr: simAddr, sda: sda, scl: scl}
+		stop = make(chan struct{})
+	)
+
+	go t.run(stop)
+	defer close(stop)
+
+	chip, err := gpio.OpenChip(sim.DevPath())
+	if err != nil {
+		log.Fatalf("OpenChip: %s", err)
+	}
+
+	defer chip.Close()
+
+	bus, err := bitbang.Open(chip, 0, 1, 0)
+	if err != nil {
+		log.Fatalf("bitbang.Open: %s", err)
+	}
+
+	defer bus.Close()
+
+	bus.SetClock(simClock)
+	bus.StretchTimeout = time.Second
+
+	var pattern = []byte{0xde, 0xad, 0xbe, 0xef}
+
+	if err := bus.Txn(simAddr, append([]byte{0x10}, pattern...), nil); err != nil {
+		log.Fatalf("write: %s", err)
+	}
+
+	var readback = make([]byte, len(pattern))
+	if err := bus.Txn(simAddr, []byte{0x10}, readback); err != nil {
+		log.Fatalf("read: %s", err)
+	}
+
+	if !bytes.Equal(readback, pattern) {
+		log.Fatalf("read back % x, expected % x", readback, pattern)
+	}
+
+	fmt.Printf("write/read back: ok\n")
+
+	if _, err := bus.ReadReg(simAddr+1, 0); !errors.Is(err, unix.ENXIO) {
+		log.Fatalf("absent target: expected ENXIO, got %v", err)
+	}
+
+	fmt.Printf("absent target NAK: ok\n")
+}
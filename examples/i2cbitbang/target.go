@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log"
+
+	"go.pdmccormick.com/linuxuapi/gpio/gpiosim"
+)
+
+// An emulated 24C02-style EEPROM on gpio-sim lines. The lines are polled, so
+// the master must be clocked slowly enough for every edge to be seen, and
+// the target stretches the clock while it updates SDA.
+
+type phase int
+
+const (
+	phaseIdle phase = iota
+	phaseAddress
+	phaseWrite
+	phaseRead
+)
+
+type target struct {
+	addr     byte
+	mem      [256]byte
+	sda, scl *gpiosim.Line
+
+	phase   phase
+	clock   int
+	cur     byte
+	ptr     byte
+	gotPtr  bool
+	acked   bool
+	read    bool
+	readAck bool
+}
+
+func (t *target) run(stop <-chan struct{}) {
+	var prevScl, prevSda = true, true
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		scl, err := t.scl.Value()
+		if err != nil {
+			log.Fatalf("target: %s", err)
+		}
+
+		sda, err := t.sda.Value()
+		if err != nil {
+			log.Fatalf("target: %s", err)
+		}
+
+		switch {
+		case prevScl && scl && prevSda && !sda:
+			t.phase, t.clock, t.cur = phaseAddress, 0, 0
+
+		case prevScl && scl && !prevSda && sda:
+			t.phase = phaseIdle
+			t.drive(true)
+
+		case !prevScl && scl:
+			t.rising(sda)
+
+		case prevScl && !scl:
+			// Hold the clock low until SDA is in place
+			t.scl.SetPull(false)
+			t.falling()
+			t.scl.SetPull(true)
+		}
+
+		prevScl, prevSda = scl, sda
+	}
+}
+
+func (t *target) drive(high bool) { t.sda.SetPull(high) }
+
+func (t *target) rising(sda bool) {
+	t.clock++
+
+	switch {
+	case t.clock <= 8 && (t.phase == phaseAddress || t.phase == phaseWrite):
+		t.cur <<= 1
+		if sda {
+			t.cur |= 1
+		}
+
+	case t.clock == 9 && t.phase == phaseRead:
+		t.readAck = !sda
+	}
+}
+
+func (t *target) falling() {
+	switch {
+	case t.clock == 8 && t.phase == phaseAddress:
+		t.acked = t.cur>>1 == t.addr
+		t.read = t.cur&1 != 0
+		t.drive(!t.acked)
+
+	case t.clock == 8 && t.phase == phaseWrite:
+		if !t.gotPtr {
+			t.ptr, t.gotPtr = t.cur, true
+		} else {
+			t.mem[t.ptr] = t.cur
+			t.ptr++
+		}
+		t.drive(false)
+
+	case t.clock == 8 && t.phase == phaseRead:
+		// Let the master acknowledge
+		t.ptr++
+		t.drive(true)
+
+	case t.clock == 9:
+		t.clock, t.cur = 0, 0
+		t.drive(true)
+
+		switch {
+		case t.phase == phaseAddress && !t.acked:
+			t.phase = phaseIdle
+
+		case t.phase == phaseAddress && t.read:
+			t.phase = phaseRead
+			t.drive(t.mem[t.ptr]&0x80 != 0)
+
+		case t.phase == phaseAddress:
+			t.phase, t.gotPtr = phaseWrite, false
+
+		case t.phase == phaseRead && t.readAck:
+			t.drive(t.mem[t.ptr]&0x80 != 0)
+
+		case t.phase == phaseRead:
+			t.phase = phaseIdle
+		}
+
+	case t.phase == phaseRead && t.clock < 8:
+		t.drive(t.mem[t.ptr]&(0x80>>t.clock) != 0)
+	}
+}
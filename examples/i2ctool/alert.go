@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"go.pdmccormick.com/linuxuapi/gpio"
+	"go.pdmccormick.com/linuxuapi/i2c/smbalert"
+)
+
+func cmdAlert(args []string) {
+	var (
+		fs           = flag.NewFlagSet("alert", flag.ExitOnError)
+		devFlag      = fs.String("d", "", "character device path `/dev/i2c-NN` or CP2112 `/dev/hidrawNN`")
+		chipFlag     = fs.String("chip", "", "GPIO `chip` with the SMBALERT# line, default poll the ARA")
+		lineFlag     = fs.Int("line", -1, "SMBALERT# line `offset`")
+		intervalFlag = fs.Duration("interval", smbalert.DefaultPollInterval, "poll `interval`")
+	)
+
+	fs.Parse(args)
+
+	if *devFlag == "" {
+		log.Fatalf("missing `-d` flag")
+	}
+
+	dev, err := openBus(*devFlag)
+	if err != nil {
+		log.Fatalf("open: %s", err)
+	}
+
+	defer dev.Close()
+
+	var mgr = smbalert.NewManager(dev)
+	mgr.PollInterval = *intervalFlag
+	mgr.Events = make(chan smbalert.Alert)
+
+	if *chipFlag != "" {
+		chip, err := gpio.OpenChip(*chipFlag)
+		if err != nil {
+			log.Fatalf("OpenChip: %s", err)
+		}
+
+		if err := mgr.UseLine(chip, *lineFlag, 0); err != nil {
+			log.Fatalf("UseLine: %s", err)
+		}
+
+		chip.Close()
+	}
+
+	var sigs = make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+
+	go func() {
+		<-sigs
+		mgr.Close()
+	}()
+
+	go func() {
+		for alert := range mgr.Events {
+			fmt.Printf("%s alert from 0x%02x flag=%t\n", alert.Time.Format(time.StampMicro), alert.Addr, alert.Flag)
+		}
+	}()
+
+	if err := mgr.Run(); err != nil && !errors.Is(err, smbalert.ErrClosed) {
+		log.Fatalf("alert: %s", err)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+	"go.pdmccormick.com/linuxuapi/i2c/cp2112"
+)
+
+// Open either a native adapter `/dev/i2c-NN` or a CP2112 bridge `/dev/hidrawNN`
+func openBus(name string) (i2c.Bus, error) {
+	if strings.HasPrefix(filepath.Base(name), "hidraw") {
+		return cp2112.OpenDevice(name)
+	}
+
+	return i2c.OpenDevice(name)
+}
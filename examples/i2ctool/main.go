@@ -5,10 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strconv"
 	"strings"
-
-	"go.pdmccormick.com/linuxuapi/i2c"
 )
 
 func fromHexString(noun, value string) uint64 {
@@ -26,9 +25,24 @@ func fromHexString(noun, value string) uint64 {
 	return n
 }
 
+var commands = map[string]func(args []string){
+	"snapshot": cmdSnapshot,
+	"diff":     cmdDiff,
+	"restore":  cmdRestore,
+	"sniff":    cmdSniff,
+	"alert":    cmdAlert,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	var (
-		devFlag   = flag.String("d", "", "character device path `/dev/i2c-NN`")
+		devFlag   = flag.String("d", "", "character device path `/dev/i2c-NN` or CP2112 `/dev/hidrawNN`")
 		addrFlag  = flag.Int("a", -1, "slave `addr`ess")
 		regFlag   = flag.Int("r", -1, "register `addr`ess")
 		writeFlag = flag.Int("w", -1, "write byte to register")
@@ -58,9 +72,9 @@ func main() {
 
 	fmt.Printf("Using device %s, address 0x%02x\n", devName, addr)
 
-	dev, err := i2c.OpenDevice(devName)
+	dev, err := openBus(devName)
 	if err != nil {
-		log.Fatalf("OpenDevice: %s", err)
+		log.Fatalf("open: %s", err)
 	}
 
 	defer dev.Close()
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// Snapshot
+type Snapshot struct {
+	Bus       string    `json:"bus"`
+	Address   uint16    `json:"address"`
+	Timestamp time.Time `json:"timestamp"`
+	Start     int       `json:"start"`
+	Registers HexBytes  `json:"registers"`
+}
+
+// HexBytes marshals as a hex string
+type HexBytes []byte
+
+func (b HexBytes) MarshalText() ([]byte, error) { return []byte(hex.EncodeToString(b)), nil }
+
+func (b *HexBytes) UnmarshalText(text []byte) (err error) {
+	*b, err = hex.DecodeString(string(text))
+	return
+}
+
+func (snap *Snapshot) End() int { return snap.Start + len(snap.Registers) - 1 }
+
+func (snap *Snapshot) Reg(reg int) (byte, bool) {
+	if reg < snap.Start || reg > snap.End() {
+		return 0, false
+	}
+	return snap.Registers[reg-snap.Start], true
+}
+
+func takeSnapshot(dev i2c.Bus, bus string, addr uint16, start, end int) (*Snapshot, error) {
+	var snap = Snapshot{
+		Bus:       bus,
+		Address:   addr,
+		Timestamp: time.Now().UTC(),
+		Start:     start,
+	}
+
+	for reg := start; reg <= end; reg++ {
+		v, err := dev.ReadReg(addr, byte(reg))
+		if err != nil {
+			return nil, fmt.Errorf("register 0x%02x: %w", reg, err)
+		}
+
+		snap.Registers = append(snap.Registers, v)
+	}
+
+	return &snap, nil
+}
+
+func (snap *Snapshot) writeJSON(w io.Writer) error {
+	var enc = json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+// Lines in the style of `i2cdump`, with metadata as comments
+func (snap *Snapshot) writeHex(w io.Writer) error {
+	var bw = bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "# bus %s\n", snap.Bus)
+	fmt.Fprintf(bw, "# address 0x%02x\n", snap.Address)
+	fmt.Fprintf(bw, "# timestamp %s\n", snap.Timestamp.Format(time.RFC3339))
+
+	for row := snap.Start &^ 0xf; row <= snap.End(); row += 16 {
+		fmt.Fprintf(bw, "%02x:", row)
+
+		for reg := row; reg < row+16; reg++ {
+			if v, ok := snap.Reg(reg); ok {
+				fmt.Fprintf(bw, " %02x", v)
+			} else {
+				fmt.Fprint(bw, " --")
+			}
+		}
+
+		fmt.Fprintln(bw)
+	}
+
+	return bw.Flush()
+}
+
+func readSnapshot(name string) (*Snapshot, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap Snapshot
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		return &snap, nil
+	}
+
+	if err := snap.parseHex(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return &snap, nil
+}
+
+func (snap *Snapshot) parseHex(data []byte) error {
+	var (
+		regs  = make(map[int]byte)
+		first = -1
+		last  = -1
+	)
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		if meta, ok := strings.CutPrefix(line, "#"); ok {
+			key, value, _ := strings.Cut(strings.TrimSpace(meta), " ")
+
+			switch key {
+			case "bus":
+				snap.Bus = value
+
+			case "address":
+				v, err := strconv.ParseUint(value, 0, 16)
+				if err != nil {
+					return fmt.Errorf("line %d: bad address: %w", n+1, err)
+				}
+				snap.Address = uint16(v)
+
+			case "timestamp":
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return fmt.Errorf("line %d: bad timestamp: %w", n+1, err)
+				}
+				snap.Timestamp = t
+			}
+
+			continue
+		}
+
+		rowStr, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: missing row offset", n+1)
+		}
+
+		row, err := strconv.ParseUint(rowStr, 16, 8)
+		if err != nil {
+			return fmt.Errorf("line %d: bad row offset: %w", n+1, err)
+		}
+
+		for i, field := range strings.Fields(rest) {
+			if field == "--" || field == "XX" {
+				continue
+			}
+
+			v, err := strconv.ParseUint(field, 16, 8)
+			if err != nil {
+				return fmt.Errorf("line %d: bad value `%s`: %w", n+1, field, err)
+			}
+
+			var reg = int(row) + i
+			regs[reg] = byte(v)
+
+			if first < 0 || reg < first {
+				first = reg
+			}
+			last = max(last, reg)
+		}
+	}
+
+	if first < 0 {
+		return fmt.Errorf("no register values")
+	}
+
+	snap.Start = first
+	snap.Registers = make(HexBytes, last-first+1)
+
+	for reg := first; reg <= last; reg++ {
+		v, ok := regs[reg]
+		if !ok {
+			return fmt.Errorf("register 0x%02x missing, snapshots must be contiguous", reg)
+		}
+		snap.Registers[reg-first] = v
+	}
+
+	return nil
+}
+
+// RegSet
+type RegSet map[int]bool
+
+// Parse a list such as `0x00,0x10-0x1f`
+func parseRegSet(s string) (RegSet, error) {
+	var set = make(RegSet)
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(item, "-")
+		if !isRange {
+			hi = lo
+		}
+
+		first, err := strconv.ParseUint(strings.TrimSpace(lo), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("bad register `%s`: %w", lo, err)
+		}
+
+		last, err := strconv.ParseUint(strings.TrimSpace(hi), 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("bad register `%s`: %w", hi, err)
+		}
+
+		for reg := first; reg <= last; reg++ {
+			set[int(reg)] = true
+		}
+	}
+
+	return set, nil
+}
+
+type regDiff struct {
+	reg  int
+	a, b byte
+}
+
+func diffSnapshots(a, b *Snapshot) (diffs []regDiff) {
+	for reg := max(a.Start, b.Start); reg <= min(a.End(), b.End()); reg++ {
+		va, _ := a.Reg(reg)
+		vb, _ := b.Reg(reg)
+
+		if va != vb {
+			diffs = append(diffs, regDiff{reg, va, vb})
+		}
+	}
+
+	return
+}
+
+func openTarget(devName string, addr int, snap *Snapshot) (i2c.Bus, string, uint16) {
+	if devName == "" && snap != nil {
+		devName = snap.Bus
+	}
+
+	if addr < 0 && snap != nil {
+		addr = int(snap.Address)
+	}
+
+	if devName == "" {
+		log.Fatalf("missing `-d` flag")
+	}
+
+	if addr < 0 {
+		log.Fatalf("missing `-a` flag")
+	}
+
+	dev, err := openBus(devName)
+	if err != nil {
+		log.Fatalf("open: %s", err)
+	}
+
+	return dev, devName, uint16(addr)
+}
+
+func cmdSnapshot(args []string) {
+	var (
+		fs         = flag.NewFlagSet("snapshot", flag.ExitOnError)
+		devFlag    = fs.String("d", "", "character device path `/dev/i2c-NN` or CP2112 `/dev/hidrawNN`")
+		addrFlag   = fs.Int("a", -1, "slave `addr`ess")
+		startFlag  = fs.Int("start", 0x00, "first register")
+		endFlag    = fs.Int("end", 0xff, "last register")
+		formatFlag = fs.String("format", "json", "output format, `json` or `hex`")
+		outFlag    = fs.String("o", "", "output `file`, default stdout")
+	)
+
+	fs.Parse(args)
+
+	if *startFlag < 0 || *endFlag > 0xff || *startFlag > *endFlag {
+		log.Fatalf("bad register range 0x%02x-0x%02x", *startFlag, *endFlag)
+	}
+
+	dev, bus, addr := openTarget(*devFlag, *addrFlag, nil)
+	defer dev.Close()
+
+	snap, err := takeSnapshot(dev, bus, addr, *startFlag, *endFlag)
+	if err != nil {
+		log.Fatalf("snapshot: %s", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if name := *outFlag; name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			log.Fatalf("create: %s", err)
+		}
+
+		defer f.Close()
+		w = f
+	}
+
+	switch *formatFlag {
+	case "json":
+		err = snap.writeJSON(w)
+	case "hex":
+		err = snap.writeHex(w)
+	default:
+		log.Fatalf("unknown format `%s`", *formatFlag)
+	}
+
+	if err != nil {
+		log.Fatalf("write: %s", err)
+	}
+}
+
+func cmdDiff(args []string) {
+	var (
+		fs       = flag.NewFlagSet("diff", flag.ExitOnError)
+		devFlag  = fs.String("d", "", "compare against live device `/dev/i2c-NN`, default from snapshot")
+		addrFlag = fs.Int("a", -1, "slave `addr`ess, default from snapshot")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: i2ctool diff [flags] a.snap [b.snap]\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	a, err := readSnapshot(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("read: %s", err)
+	}
+
+	var b *Snapshot
+
+	if fs.NArg() == 2 {
+		if b, err = readSnapshot(fs.Arg(1)); err != nil {
+			log.Fatalf("read: %s", err)
+		}
+	} else {
+		dev, bus, addr := openTarget(*devFlag, *addrFlag, a)
+		defer dev.Close()
+
+		if b, err = takeSnapshot(dev, bus, addr, a.Start, a.End()); err != nil {
+			log.Fatalf("snapshot: %s", err)
+		}
+	}
+
+	var diffs = diffSnapshots(a, b)
+
+	for _, d := range diffs {
+		fmt.Printf("0x%02x: 0x%02x -> 0x%02x\n", d.reg, d.a, d.b)
+	}
+
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func cmdRestore(args []string) {
+	var (
+		fs          = flag.NewFlagSet("restore", flag.ExitOnError)
+		devFlag     = fs.String("d", "", "character device path `/dev/i2c-NN`, default from snapshot")
+		addrFlag    = fs.Int("a", -1, "slave `addr`ess, default from snapshot")
+		excludeFlag = fs.String("x", "", "registers to never write, as in `0x00,0x10-0x1f`")
+		dryRunFlag  = fs.Bool("n", false, "show writes without performing them")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: i2ctool restore [flags] file.snap\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	exclude, err := parseRegSet(*excludeFlag)
+	if err != nil {
+		log.Fatalf("-x: %s", err)
+	}
+
+	snap, err := readSnapshot(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("read: %s", err)
+	}
+
+	dev, bus, addr := openTarget(*devFlag, *addrFlag, snap)
+	defer dev.Close()
+
+	live, err := takeSnapshot(dev, bus, addr, snap.Start, snap.End())
+	if err != nil {
+		log.Fatalf("snapshot: %s", err)
+	}
+
+	var failed int
+
+	for _, d := range diffSnapshots(live, snap) {
+		if exclude[d.reg] {
+			fmt.Printf("0x%02x: skip 0x%02x -> 0x%02x (excluded)\n", d.reg, d.a, d.b)
+			continue
+		}
+
+		fmt.Printf("0x%02x: write 0x%02x -> 0x%02x\n", d.reg, d.a, d.b)
+
+		if *dryRunFlag {
+			continue
+		}
+
+		if err := dev.WriteReg(addr, byte(d.reg), d.b); err != nil {
+			log.Fatalf("WriteReg: %s", err)
+		}
+
+		// Read-only and self-clearing registers will not hold the value
+		if v, err := dev.ReadReg(addr, byte(d.reg)); err != nil {
+			log.Fatalf("ReadReg: %s", err)
+		} else if v != d.b {
+			fmt.Printf("0x%02x: reads back 0x%02x, not writable?\n", d.reg, v)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.pdmccormick.com/linuxuapi/i2c/i2ctrace"
+)
+
+// Accept `1`, `i2c-1` or `/dev/i2c-1`
+func parseAdapter(s string) (int, error) {
+	s = strings.TrimPrefix(s, "/dev/")
+	s = strings.TrimPrefix(s, "i2c-")
+
+	return strconv.Atoi(s)
+}
+
+func cmdSniff(args []string) {
+	var (
+		fs       = flag.NewFlagSet("sniff", flag.ExitOnError)
+		busFlag  = fs.String("b", "", "comma separated `adapters` such as `1,i2c-3`, default all")
+		addrFlag = fs.Int("a", -1, "only show transactions involving slave `addr`ess")
+		nameFlag = fs.String("name", i2ctrace.DefaultInstance, "tracefs instance `name`")
+		outFlag  = fs.String("o", "", "output `file`, default stdout")
+	)
+
+	fs.Parse(args)
+
+	var adapters []int
+	for _, item := range strings.Split(*busFlag, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		nr, err := parseAdapter(item)
+		if err != nil {
+			log.Fatalf("bad adapter `%s`", item)
+		}
+
+		adapters = append(adapters, nr)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outFlag != "" {
+		f, err := os.Create(*outFlag)
+		if err != nil {
+			log.Fatalf("create: %s", err)
+		}
+
+		defer f.Close()
+		out = f
+	}
+
+	tracer, err := i2ctrace.Open(*nameFlag, adapters...)
+	if err != nil {
+		log.Fatalf("i2ctrace.Open: %s", err)
+	}
+
+	// Closing removes the tracefs instance, which would otherwise stay
+	// behind and make the next Open with the same name fail
+	var (
+		closeOnce   sync.Once
+		closeTracer = func() { closeOnce.Do(func() { tracer.Close() }) }
+	)
+
+	defer closeTracer()
+
+	var sigs = make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+
+	go func() {
+		<-sigs
+		closeTracer()
+	}()
+
+	var (
+		ch   = make(chan *i2ctrace.Transaction)
+		done = make(chan error, 1)
+	)
+
+	go func() {
+		done <- tracer.Run(ch)
+		close(ch)
+	}()
+
+	for txn := range ch {
+		if *addrFlag >= 0 && !involves(txn, uint16(*addrFlag)) {
+			continue
+		}
+
+		fmt.Fprintln(out, txn)
+	}
+
+	if err := <-done; err != nil {
+		closeTracer()
+		log.Fatalf("sniff: %s", err)
+	}
+}
+
+func involves(txn *i2ctrace.Transaction, addr uint16) bool {
+	for _, msg := range txn.Msgs {
+		if msg.Addr == addr {
+			return true
+		}
+	}
+	return false
+}
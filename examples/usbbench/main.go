@@ -0,0 +1,373 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"go.pdmccormick.com/linuxuapi/usb/usbdevfs"
+	"go.pdmccormick.com/linuxuapi/usb/usbgadget/gadgetconfig"
+	"golang.org/x/sys/unix"
+)
+
+// Measure bulk throughput to a SourceSink or Loopback function, checking the
+// data against its pattern. To run end to end on one machine:
+//
+//	modprobe dummy_hcd
+//	usbbench -mk -udc dummy_udc.0 -fn sourcesink
+//
+// which creates the gadget, runs the benchmark against it through the dummy
+// host controller, and removes it again. Without `-mk`, it runs against an
+// existing gadget, such as one made from gadgetctl's zero.json, selecting
+// the configuration with `-config`.
+
+const serialNumber = "usbbench"
+
+var (
+	vidFlag     = flag.Int("vid", 0x0525, "vendor `id`")
+	pidFlag     = flag.Int("pid", 0xa4a0, "product `id`")
+	fnFlag      = flag.String("fn", "sourcesink", "`function` to test, sourcesink or loopback")
+	patternFlag = flag.String("pattern", gadgetconfig.SourceSinkMod63, "data `pattern`, zeros, mod63 or none to skip checking")
+	mkFlag      = flag.Bool("mk", false, "create the gadget for the run, and remove it after")
+	udcFlag     = flag.String("udc", "", "with -mk, bind to `udc`, default the first found")
+	configFlag  = flag.Int("config", 0, "select configuration `n`, default the active one")
+	ifaceFlag   = flag.Int("iface", 0, "interface `number` of the function")
+	lenFlag     = flag.Int("n", 64<<20, "`bytes` to transfer each way")
+	sizeFlag    = flag.Int("size", 64<<10, "`bytes` per transfer")
+	timeoutFlag = flag.Duration("timeout", 5*time.Second, "per transfer `timeout`")
+)
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+
+	switch *patternFlag {
+	case gadgetconfig.SourceSinkZeros, gadgetconfig.SourceSinkMod63, gadgetconfig.SourceSinkNone:
+	default:
+		log.Fatalf("unknown pattern `%s`", *patternFlag)
+	}
+
+	if *mkFlag {
+		g, err := makeGadget()
+		if err != nil {
+			log.Fatalf("create gadget: %s", err)
+		}
+
+		err = run()
+
+		if rmErr := g.Remove(); rmErr != nil {
+			log.Printf("remove gadget: %s", rmErr)
+		}
+
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		return
+	}
+
+	if err := run(); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
+
+func makeGadget() (*gadgetconfig.Gadget, error) {
+	var fn gadgetconfig.Function
+
+	switch *fnFlag {
+	case "sourcesink":
+		fn = &gadgetconfig.SourceSinkFunction{Name: "0", Pattern: *patternFlag}
+	case "loopback":
+		fn = &gadgetconfig.LoopbackFunction{Name: "0"}
+	default:
+		return nil, fmt.Errorf("unknown function `%s`", *fnFlag)
+	}
+
+	var udc = *udcFlag
+	if udc == "" {
+		udcs := gadgetconfig.FindUdc()
+		if len(udcs) == 0 {
+			return nil, errors.New("no UDC found")
+		}
+		udc = udcs[0]
+	}
+
+	var g = gadgetconfig.Gadget{
+		Name:         "usbbench",
+		IdVendor:     *vidFlag,
+		IdProduct:    *pidFlag,
+		SerialNumber: serialNumber,
+		Manufacturer: "Example Manufacturer",
+		Product:      "usbbench " + *fnFlag,
+		UDC:          udc,
+		Configs: []gadgetconfig.Config{
+			{
+				Name:          "c.1",
+				Configuration: *fnFlag,
+				Functions:     []gadgetconfig.Function{fn},
+			},
+		},
+	}
+
+	if g.Exists() {
+		return nil, fmt.Errorf("gadget `%s` already exists", g.Name)
+	}
+
+	if err := g.Create(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("gadget `%s` bound to %s", g.Name, udc)
+	return &g, nil
+}
+
+// Wait for the host side to enumerate the device
+func findDevice(wait time.Duration) (*usbdevfs.DeviceInfo, error) {
+	var deadline = time.Now().Add(wait)
+
+	for {
+		devs, err := usbdevfs.FindDevices(*vidFlag, *pidFlag)
+		if err != nil && !*mkFlag {
+			return nil, err
+		}
+
+		for i := range devs {
+			if !*mkFlag || devs[i].SerialNumber == serialNumber {
+				return &devs[i], nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no device %04x:%04x found", *vidFlag, *pidFlag)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func run() error {
+	var wait time.Duration
+	if *mkFlag {
+		wait = 5 * time.Second
+	}
+
+	info, err := findDevice(wait)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("device %s: %s %s, %d Mbit/s", info.DevPath(), info.Manufacturer, info.Product, info.Speed)
+
+	dev, err := info.Open()
+	if err != nil {
+		return err
+	}
+
+	defer dev.Close()
+
+	var iface = *ifaceFlag
+
+	// Any driver in the current configuration, such as usbtest, has to let
+	// go before selecting another, which probes drivers for its interfaces
+	// all over again
+	if c := *configFlag; c != 0 {
+		if err := detach(dev, iface); err != nil {
+			return err
+		}
+
+		if err := dev.SetConfiguration(c); err != nil {
+			return fmt.Errorf("set configuration %d: %w", c, err)
+		}
+	}
+
+	if err := detach(dev, iface); err != nil {
+		return err
+	}
+
+	if err := dev.ClaimInterface(iface); err != nil {
+		return fmt.Errorf("claim interface %d: %w", iface, err)
+	}
+
+	defer dev.ReleaseInterface(iface)
+
+	in, out, err := bulkEndpoints(info, iface)
+	if err != nil {
+		return err
+	}
+
+	if *sizeFlag <= 0 || *sizeFlag%in.MaxPacketSize != 0 {
+		return fmt.Errorf("transfer size %d must be a multiple of the %d byte packet size", *sizeFlag, in.MaxPacketSize)
+	}
+
+	// A short last transfer would overflow, as the gadget sends whole packets
+	if *lenFlag <= 0 || *lenFlag%in.MaxPacketSize != 0 {
+		return fmt.Errorf("length %d must be a multiple of the %d byte packet size", *lenFlag, in.MaxPacketSize)
+	}
+
+	var b = bench{dev: dev, in: in, out: out, pattern: *patternFlag}
+
+	switch *fnFlag {
+	case "sourcesink":
+		if err := b.report("source", b.source); err != nil {
+			return err
+		}
+		return b.report("sink", b.sink)
+
+	case "loopback":
+		return b.report("loopback", b.loopback)
+
+	default:
+		return fmt.Errorf("unknown function `%s`", *fnFlag)
+	}
+}
+
+func detach(dev *usbdevfs.Device, iface int) error {
+	if err := dev.DetachKernelDriver(iface); err != nil && err != unix.ENODATA {
+		return fmt.Errorf("detach kernel driver: %w", err)
+	}
+	return nil
+}
+
+func bulkEndpoints(info *usbdevfs.DeviceInfo, iface int) (in, out usbdevfs.Endpoint, err error) {
+	eps, err := info.Endpoints(iface)
+	if err != nil {
+		return
+	}
+
+	var haveIn, haveOut bool
+
+	for _, ep := range eps {
+		switch {
+		case ep.Type != usbdevfs.EndpointBulk:
+		case ep.In() && !haveIn:
+			in, haveIn = ep, true
+		case !ep.In() && !haveOut:
+			out, haveOut = ep, true
+		}
+	}
+
+	if !haveIn || !haveOut {
+		err = fmt.Errorf("interface %d has no bulk IN and OUT endpoints", iface)
+	}
+
+	return
+}
+
+// bench
+type bench struct {
+	dev     *usbdevfs.Device
+	in, out usbdevfs.Endpoint
+	pattern string
+}
+
+func (b *bench) report(name string, test func() (int, error)) error {
+	var start = time.Now()
+
+	n, err := test()
+	if err != nil {
+		return fmt.Errorf("%s: after %d bytes: %w", name, n, err)
+	}
+
+	var elapsed = time.Since(start)
+
+	log.Printf("%s: %d bytes in %s, %.1f MB/s", name, n, elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds()/1e6)
+	return nil
+}
+
+// Pattern byte at an offset into the stream. Transfers are whole packets,
+// so the offset within a packet is the same as in the gadget's requests.
+func (b *bench) fill(p []byte, off int) {
+	for i := range p {
+		p[i] = b.expect(off + i)
+	}
+}
+
+func (b *bench) expect(off int) byte {
+	if b.pattern == gadgetconfig.SourceSinkMod63 {
+		return byte(off % b.in.MaxPacketSize % 63)
+	}
+	return 0
+}
+
+func (b *bench) check(p []byte, off int) error {
+	if b.pattern == gadgetconfig.SourceSinkNone {
+		return nil
+	}
+
+	for i, v := range p {
+		if want := b.expect(off + i); v != want {
+			return fmt.Errorf("byte %d is 0x%02x, expected 0x%02x", off+i, v, want)
+		}
+	}
+
+	return nil
+}
+
+func (b *bench) transfer(ep usbdevfs.Endpoint, p []byte) (int, error) {
+	n, err := b.dev.Bulk(ep.Address, p, *timeoutFlag)
+	if err == unix.EPIPE {
+		// The gadget halts the endpoint when it finds bad data
+		b.dev.ClearHalt(ep.Address)
+		return n, fmt.Errorf("endpoint 0x%02x halted", ep.Address)
+	}
+	return n, err
+}
+
+// Read from the IN endpoint
+func (b *bench) source() (total int, err error) {
+	var buf = make([]byte, *sizeFlag)
+
+	for total < *lenFlag {
+		n, err := b.transfer(b.in, buf[:min(len(buf), *lenFlag-total)])
+		if err != nil {
+			return total, err
+		}
+
+		if err := b.check(buf[:n], total); err != nil {
+			return total, err
+		}
+
+		total += n
+	}
+
+	return total, nil
+}
+
+// Write to the OUT endpoint, for the gadget to check
+func (b *bench) sink() (total int, err error) {
+	var buf = make([]byte, *sizeFlag)
+	b.fill(buf, 0)
+
+	for total < *lenFlag {
+		n, err := b.transfer(b.out, buf[:min(len(buf), *lenFlag-total)])
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+	}
+
+	return total, nil
+}
+
+// Write to the OUT endpoint while reading it back from the IN endpoint
+func (b *bench) loopback() (int, error) {
+	var writeErr = make(chan error, 1)
+
+	go func() {
+		_, err := b.sink()
+		writeErr <- err
+	}()
+
+	total, err := b.source()
+	if err != nil {
+		return total, err
+	}
+
+	if err := <-writeErr; err != nil {
+		return total, fmt.Errorf("write: %w", err)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"go.pdmccormick.com/linuxuapi/usb/usbgadget/printergadget"
+)
+
+// Accept print jobs from the host, as a printer that is always online
+
+func main() {
+	var (
+		devFlag    = flag.String("dev", printergadget.DefaultDevice, "`path` to printer gadget device")
+		outFlag    = flag.String("o", "", "append job data to `file` rather than stdout")
+		statusFlag = flag.Bool("status", false, "print the current status and exit")
+		paperFlag  = flag.Bool("nopaper", false, "report the printer as out of paper")
+	)
+
+	flag.Parse()
+	log.SetFlags(0)
+
+	dev, err := printergadget.OpenDevice(*devFlag)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	defer dev.Close()
+
+	if *statusFlag {
+		s, err := dev.Status()
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		log.Printf("status 0x%02x ok %v", byte(s), s.Ok())
+		return
+	}
+
+	var status = printergadget.StatusNotError | printergadget.StatusSelected
+	if *paperFlag {
+		status |= printergadget.StatusPaperEmpty
+	}
+
+	if err := dev.SetStatus(status); err != nil {
+		log.Fatalf("set status: %s", err)
+	}
+
+	var out io.Writer = os.Stdout
+
+	if name := *outFlag; name != "" {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		defer f.Close()
+		out = f
+	}
+
+	if _, err := io.Copy(out, dev); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
@@ -0,0 +1,134 @@
+package gpio
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"go.pdmccormick.com/linuxuapi/internal/ioctl"
+)
+
+// See <https://docs.kernel.org/userspace-api/gpio/chardev.html>
+
+const ChipGlob = "/dev/gpiochip*"
+
+// LineFlags
+type LineFlags uint64
+
+const (
+	FlagUsed               LineFlags = 1 << 0
+	FlagActiveLow          LineFlags = 1 << 1
+	FlagInput              LineFlags = 1 << 2
+	FlagOutput             LineFlags = 1 << 3
+	FlagEdgeRising         LineFlags = 1 << 4
+	FlagEdgeFalling        LineFlags = 1 << 5
+	FlagOpenDrain          LineFlags = 1 << 6
+	FlagOpenSource         LineFlags = 1 << 7
+	FlagBiasPullUp         LineFlags = 1 << 8
+	FlagBiasPullDown       LineFlags = 1 << 9
+	FlagBiasDisabled       LineFlags = 1 << 10
+	FlagEventClockRealtime LineFlags = 1 << 11
+	FlagEventClockHte      LineFlags = 1 << 12
+
+	FlagEdgeBoth = FlagEdgeRising | FlagEdgeFalling
+)
+
+// Chip
+type Chip struct {
+	f        *os.File
+	Name     string
+	Label    string
+	NumLines int
+}
+
+// Find all GPIO chip character devices
+func FindChips() []string {
+	names, _ := filepath.Glob(ChipGlob)
+	return names
+}
+
+// Open a chip by path, or by name such as `gpiochip0`
+func OpenChip(name string) (chip *Chip, err error) {
+	var f *os.File
+
+	defer func() {
+		if err != nil && f != nil {
+			f.Close()
+		}
+	}()
+
+	if !strings.ContainsRune(name, '/') {
+		name = filepath.Join("/dev", name)
+	}
+
+	f, err = os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+
+	chip = &Chip{f: f}
+
+	var info gpiochip_info
+	if _, err = ioctl.Pointer(f, _GPIO_GET_CHIPINFO_IOCTL, unsafe.Pointer(&info)); err != nil {
+		return nil, err
+	}
+
+	chip.Name = cString(info.name[:])
+	chip.Label = cString(info.label[:])
+	chip.NumLines = int(info.lines)
+
+	return
+}
+
+func (chip *Chip) Close() error { return chip.f.Close() }
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// LineInfo
+type LineInfo struct {
+	Offset   int
+	Name     string
+	Consumer string
+	Flags    LineFlags
+}
+
+func (info *LineInfo) Used() bool { return info.Flags&FlagUsed != 0 }
+
+func (chip *Chip) LineInfo(offset int) (*LineInfo, error) {
+	var raw = gpio_v2_line_info{offset: uint32(offset)}
+
+	if _, err := ioctl.Pointer(chip.f, _GPIO_V2_GET_LINEINFO_IOCTL, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return &LineInfo{
+		Offset:   int(raw.offset),
+		Name:     cString(raw.name[:]),
+		Consumer: cString(raw.consumer[:]),
+		Flags:    LineFlags(raw.flags),
+	}, nil
+}
+
+// Find the offset of a line by name
+func (chip *Chip) FindLine(name string) (int, error) {
+	for offset := 0; offset < chip.NumLines; offset++ {
+		info, err := chip.LineInfo(offset)
+		if err != nil {
+			return -1, err
+		}
+
+		if info.Name == name {
+			return offset, nil
+		}
+	}
+
+	return -1, fmt.Errorf("gpio: no line named `%s` on %s", name, chip.Name)
+}
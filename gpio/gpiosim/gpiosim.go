@@ -0,0 +1,159 @@
+package gpiosim
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Simulated GPIO chips, via the `gpio-sim` module's configfs interface. The
+// pull of each line stands in for whatever is wired to it, so code using
+// the chip's character device can be exercised against emulated hardware.
+// See <https://docs.kernel.org/admin-guide/gpio/gpio-sim.html>
+
+const (
+	ConfigfsPath = "/sys/kernel/config/gpio-sim"
+	PlatformPath = "/sys/devices/platform"
+)
+
+// Sim is a simulated chip with a single bank of lines
+type Sim struct {
+	dir string
+
+	DevName  string // such as `gpio-sim.0`
+	ChipName string // such as `gpiochip3`
+	NumLines int
+}
+
+// Create and bring up a simulated chip with `numLines` lines
+func Create(name string, numLines int) (sim *Sim, err error) {
+	sim = &Sim{
+		dir:      filepath.Join(ConfigfsPath, name),
+		NumLines: numLines,
+	}
+
+	if err = os.Mkdir(sim.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			sim.Close()
+			sim = nil
+		}
+	}()
+
+	var bank = filepath.Join(sim.dir, "bank0")
+
+	if err = os.Mkdir(bank, 0o755); err != nil {
+		return
+	}
+
+	if err = writeFile(filepath.Join(bank, "label"), name); err != nil {
+		return
+	}
+
+	if err = writeFile(filepath.Join(bank, "num_lines"), strconv.Itoa(numLines)); err != nil {
+		return
+	}
+
+	if err = writeFile(filepath.Join(sim.dir, "live"), "1"); err != nil {
+		return
+	}
+
+	if sim.DevName, err = readFile(filepath.Join(sim.dir, "dev_name")); err != nil {
+		return
+	}
+
+	sim.ChipName, err = readFile(filepath.Join(bank, "chip_name"))
+	return
+}
+
+// Take the chip down and remove its configuration
+func (sim *Sim) Close() error {
+	writeFile(filepath.Join(sim.dir, "live"), "0")
+	os.Remove(filepath.Join(sim.dir, "bank0"))
+
+	return os.Remove(sim.dir)
+}
+
+// Character device path of the chip
+func (sim *Sim) DevPath() string { return filepath.Join("/dev", sim.ChipName) }
+
+func (sim *Sim) linePath(offset int, attr string) string {
+	return filepath.Join(PlatformPath, sim.DevName, sim.ChipName, fmt.Sprintf("sim_gpio%d", offset), attr)
+}
+
+// Pull a line up or down, which is the level it reads as an input
+func (sim *Sim) SetPull(offset int, up bool) error {
+	var pull = "pull-down"
+	if up {
+		pull = "pull-up"
+	}
+
+	return writeFile(sim.linePath(offset, "pull"), pull)
+}
+
+// Current level of a line, as driven by its user or else its pull
+func (sim *Sim) Value(offset int) (bool, error) {
+	s, err := readFile(sim.linePath(offset, "value"))
+	return s == "1", err
+}
+
+// Line
+type Line struct {
+	value *os.File
+	pull  *os.File
+}
+
+// Hold a line's attributes open, for emulation loops that poll quickly
+func (sim *Sim) OpenLine(offset int) (line *Line, err error) {
+	line = &Line{}
+
+	if line.value, err = os.Open(sim.linePath(offset, "value")); err != nil {
+		return nil, err
+	}
+
+	if line.pull, err = os.OpenFile(sim.linePath(offset, "pull"), os.O_WRONLY, 0); err != nil {
+		line.value.Close()
+		return nil, err
+	}
+
+	return
+}
+
+func (line *Line) Close() error {
+	line.value.Close()
+	return line.pull.Close()
+}
+
+func (line *Line) Value() (bool, error) {
+	var buf [2]byte
+
+	if n, err := line.value.ReadAt(buf[:], 0); n == 0 {
+		return false, err
+	}
+
+	return buf[0] == '1', nil
+}
+
+func (line *Line) SetPull(up bool) error {
+	var pull = "pull-down"
+	if up {
+		pull = "pull-up"
+	}
+
+	_, err := line.pull.WriteAt([]byte(pull), 0)
+	return err
+}
+
+func writeFile(path, value string) error {
+	return os.WriteFile(path, []byte(value), 0)
+}
+
+func readFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	return strings.TrimSpace(string(data)), err
+}
@@ -0,0 +1,210 @@
+package gpio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"go.pdmccormick.com/linuxuapi/internal/ioctl"
+	"golang.org/x/sys/unix"
+)
+
+var ErrTooManyLines = errors.New("gpio: too many lines in request")
+
+// LineConfig applies to every line in a request. Bit `i` of Values and
+// ValuesMask refers to the `i`th requested line.
+type LineConfig struct {
+	Flags LineFlags
+
+	// Initial output values, for lines in ValuesMask
+	Values     uint64
+	ValuesMask uint64
+
+	Debounce time.Duration
+}
+
+func (cfg *LineConfig) toC() (out gpio_v2_line_config) {
+	out.flags = uint64(cfg.Flags)
+
+	var add = func(id uint32, value, mask uint64) {
+		out.attrs[out.numAttrs] = gpio_v2_line_config_attribute{
+			attr: gpio_v2_line_attribute{id: id, value: value},
+			mask: mask,
+		}
+		out.numAttrs++
+	}
+
+	if cfg.ValuesMask != 0 {
+		add(_GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES, cfg.Values, cfg.ValuesMask)
+	}
+
+	if cfg.Debounce > 0 {
+		add(_GPIO_V2_LINE_ATTR_ID_DEBOUNCE, uint64(cfg.Debounce/time.Microsecond), ^uint64(0))
+	}
+
+	return
+}
+
+// Lines
+type Lines struct {
+	f       *os.File
+	Offsets []int
+}
+
+// Request exclusive use of a set of lines
+func (chip *Chip) RequestLines(consumer string, offsets []int, cfg LineConfig) (*Lines, error) {
+	if len(offsets) == 0 || len(offsets) > _GPIO_V2_LINES_MAX {
+		return nil, ErrTooManyLines
+	}
+
+	var req = gpio_v2_line_request{
+		config:   cfg.toC(),
+		numLines: uint32(len(offsets)),
+	}
+
+	for i, offset := range offsets {
+		req.offsets[i] = uint32(offset)
+	}
+
+	copy(req.consumer[:len(req.consumer)-1], consumer)
+
+	if _, err := ioctl.Pointer(chip.f, _GPIO_V2_GET_LINE_IOCTL, unsafe.Pointer(&req)); err != nil {
+		return nil, fmt.Errorf("gpio: request lines %v on %s: %w", offsets, chip.Name, err)
+	}
+
+	// A non-blocking descriptor lets os.File use the poller, so reads honour
+	// deadlines and are interrupted by Close
+	if err := unix.SetNonblock(int(req.fd), true); err != nil {
+		unix.Close(int(req.fd))
+		return nil, err
+	}
+
+	return &Lines{
+		f:       os.NewFile(uintptr(req.fd), fmt.Sprintf("%s:%v", chip.Name, offsets)),
+		Offsets: append([]int(nil), offsets...),
+	}, nil
+}
+
+// Request a single line
+func (chip *Chip) RequestLine(consumer string, offset int, cfg LineConfig) (*Lines, error) {
+	return chip.RequestLines(consumer, []int{offset}, cfg)
+}
+
+func (l *Lines) Close() error { return l.f.Close() }
+
+// Change the configuration of all lines without releasing them
+func (l *Lines) Reconfigure(cfg LineConfig) error {
+	var raw = cfg.toC()
+	_, err := ioctl.Pointer(l.f, _GPIO_V2_LINE_SET_CONFIG_IOCTL, unsafe.Pointer(&raw))
+	return err
+}
+
+// Read the values of the lines in `mask`
+func (l *Lines) GetValues(mask uint64) (uint64, error) {
+	var raw = gpio_v2_line_values{mask: mask}
+
+	if _, err := ioctl.Pointer(l.f, _GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&raw)); err != nil {
+		return 0, err
+	}
+
+	return raw.bits & mask, nil
+}
+
+// Set the values of the lines in `mask`
+func (l *Lines) SetValues(bits, mask uint64) error {
+	var raw = gpio_v2_line_values{bits: bits, mask: mask}
+	_, err := ioctl.Pointer(l.f, _GPIO_V2_LINE_SET_VALUES_IOCTL, unsafe.Pointer(&raw))
+	return err
+}
+
+// Value of the `i`th requested line
+func (l *Lines) Value(i int) (bool, error) {
+	bits, err := l.GetValues(1 << i)
+	return bits != 0, err
+}
+
+// Set the `i`th requested line
+func (l *Lines) SetValue(i int, v bool) error {
+	var bits uint64
+	if v {
+		bits = 1 << i
+	}
+
+	return l.SetValues(bits, 1<<i)
+}
+
+// EventId
+type EventId uint32
+
+const (
+	EventRisingEdge  EventId = 1
+	EventFallingEdge EventId = 2
+)
+
+func (id EventId) String() string {
+	switch id {
+	case EventRisingEdge:
+		return "rising"
+	case EventFallingEdge:
+		return "falling"
+	default:
+		return fmt.Sprintf("EventId(%d)", uint32(id))
+	}
+}
+
+// LineEvent
+type LineEvent struct {
+	// Monotonic by default, or realtime with FlagEventClockRealtime
+	Timestamp time.Duration
+	Id        EventId
+	Offset    int
+	Seqno     uint32
+	LineSeqno uint32
+}
+
+const lineEventLen = int(unsafe.Sizeof(gpio_v2_line_event{}))
+
+func (l *Lines) SetReadDeadline(t time.Time) error { return l.f.SetReadDeadline(t) }
+
+// Wait for the next edge event, for lines requested with FlagEdgeRising or
+// FlagEdgeFalling
+func (l *Lines) ReadEvent() (*LineEvent, error) {
+	var buf [lineEventLen]byte
+
+	n, err := l.f.Read(buf[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if n != lineEventLen {
+		return nil, fmt.Errorf("gpio: short event read, %d bytes", n)
+	}
+
+	var ne = binary.NativeEndian
+
+	return &LineEvent{
+		Timestamp: time.Duration(ne.Uint64(buf[0:])),
+		Id:        EventId(ne.Uint32(buf[8:])),
+		Offset:    int(ne.Uint32(buf[12:])),
+		Seqno:     ne.Uint32(buf[16:]),
+		LineSeqno: ne.Uint32(buf[20:]),
+	}, nil
+}
+
+// Wait for an edge event, or until the timeout elapses, returning nil on
+// timeout
+func (l *Lines) WaitEvent(timeout time.Duration) (*LineEvent, error) {
+	if err := l.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	ev, err := l.ReadEvent()
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, nil
+	}
+
+	return ev, err
+}
@@ -0,0 +1,77 @@
+package gpio
+
+// See <linux/gpio.h>, character device uapi v2
+
+const (
+	_GPIO_GET_CHIPINFO_IOCTL            = 0x8044b401
+	_GPIO_V2_GET_LINEINFO_IOCTL         = 0xc100b405
+	_GPIO_V2_GET_LINE_IOCTL             = 0xc250b407
+	_GPIO_V2_LINE_SET_CONFIG_IOCTL      = 0xc110b40d
+	_GPIO_V2_LINE_GET_VALUES_IOCTL      = 0xc010b40e
+	_GPIO_V2_LINE_SET_VALUES_IOCTL      = 0xc010b40f
+	_GPIO_MAX_NAME_SIZE                 = 32
+	_GPIO_V2_LINES_MAX                  = 64
+	_GPIO_V2_LINE_NUM_ATTRS_MAX         = 10
+	_GPIO_V2_LINE_ATTR_ID_FLAGS         = 1
+	_GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES = 2
+	_GPIO_V2_LINE_ATTR_ID_DEBOUNCE      = 3
+)
+
+type gpiochip_info struct {
+	name  [_GPIO_MAX_NAME_SIZE]byte
+	label [_GPIO_MAX_NAME_SIZE]byte
+	lines uint32
+}
+
+type gpio_v2_line_values struct {
+	bits uint64
+	mask uint64
+}
+
+// The union is represented by its widest member
+type gpio_v2_line_attribute struct {
+	id      uint32
+	padding uint32
+	value   uint64
+}
+
+type gpio_v2_line_config_attribute struct {
+	attr gpio_v2_line_attribute
+	mask uint64
+}
+
+type gpio_v2_line_config struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [_GPIO_V2_LINE_NUM_ATTRS_MAX]gpio_v2_line_config_attribute
+}
+
+type gpio_v2_line_request struct {
+	offsets         [_GPIO_V2_LINES_MAX]uint32
+	consumer        [_GPIO_MAX_NAME_SIZE]byte
+	config          gpio_v2_line_config
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+type gpio_v2_line_info struct {
+	name     [_GPIO_MAX_NAME_SIZE]byte
+	consumer [_GPIO_MAX_NAME_SIZE]byte
+	offset   uint32
+	numAttrs uint32
+	flags    uint64
+	attrs    [_GPIO_V2_LINE_NUM_ATTRS_MAX]gpio_v2_line_attribute
+	padding  [4]uint32
+}
+
+type gpio_v2_line_event struct {
+	timestampNs uint64
+	id          uint32
+	offset      uint32
+	seqno       uint32
+	lineSeqno   uint32
+	padding     [6]uint32
+}
@@ -0,0 +1,340 @@
+package bitbang
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"go.pdmccormick.com/linuxuapi/gpio"
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// Software I2C master driving SDA and SCL as open-drain GPIO lines, after
+// the kernel's `i2c-algo-bit`. Errors follow the kernel's conventions, so
+// callers see the same errno values as from an i2c-dev adapter.
+
+const (
+	DefaultClock          = 100_000
+	DefaultStretchTimeout = 25 * time.Millisecond
+
+	// SMBus block length limit for MsgRecvLen
+	blockMax = 32
+
+	sdaLine = 0
+	sclLine = 1
+)
+
+var (
+	ErrNak            = unix.ENXIO
+	ErrDataNak        = unix.EIO
+	ErrStretchTimeout = unix.ETIMEDOUT
+	ErrArbitration    = unix.EAGAIN
+	ErrBusStuck       = errors.New("bitbang: SDA held low")
+)
+
+// Satisfied by *gpio.Lines, with SDA and SCL as lines 0 and 1
+type lineSet interface {
+	Value(i int) (bool, error)
+	SetValue(i int, v bool) error
+	Close() error
+}
+
+// Adapter
+type Adapter struct {
+	mu      sync.Mutex
+	lines   lineSet
+	offsets [2]int
+
+	// Half of the SCL period. Actual rates are lower, as every line access
+	// is a system call.
+	HalfPeriod time.Duration
+
+	// How long a target may hold SCL low
+	StretchTimeout time.Duration
+}
+
+var _ i2c.Bus = (*Adapter)(nil)
+
+// Request the `sda` and `scl` lines of `chip` as open-drain outputs, both
+// released. `flags` may add bias, such as gpio.FlagBiasPullUp where the
+// board lacks pull-up resistors.
+func Open(chip *gpio.Chip, sda, scl int, flags gpio.LineFlags) (*Adapter, error) {
+	lines, err := chip.RequestLines("i2c-bitbang", []int{sda, scl}, gpio.LineConfig{
+		Flags:      gpio.FlagOutput | gpio.FlagOpenDrain | flags,
+		Values:     1<<sdaLine | 1<<sclLine,
+		ValuesMask: 1<<sdaLine | 1<<sclLine,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var a = Adapter{
+		lines:          lines,
+		offsets:        [2]int{sda, scl},
+		HalfPeriod:     time.Second / (2 * DefaultClock),
+		StretchTimeout: DefaultStretchTimeout,
+	}
+
+	if high, err := a.getSda(); err != nil || !high {
+		if err == nil {
+			err = a.Recover()
+		}
+
+		if err != nil {
+			lines.Close()
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+func (a *Adapter) Close() error { return a.lines.Close() }
+
+// Set the nominal SCL frequency in Hz
+func (a *Adapter) SetClock(hz int) { a.HalfPeriod = time.Second / time.Duration(2*hz) }
+
+func (a *Adapter) Functionality() uintptr {
+	return i2c.FuncI2c | i2c.Func10BitAddr | i2c.FuncProtocolMangling |
+		i2c.FuncSmbusQuick |
+		i2c.FuncSmbusReadByte | i2c.FuncSmbusWriteByte |
+		i2c.FuncSmbusReadByteData | i2c.FuncSmbusWriteByteData |
+		i2c.FuncSmbusReadWordData | i2c.FuncSmbusWriteWordData |
+		i2c.FuncSmbusProcCall |
+		i2c.FuncSmbusReadBlockData | i2c.FuncSmbusWriteBlockData |
+		i2c.FuncSmbusReadI2cBlock | i2c.FuncSmbusWriteI2cBlock
+}
+
+func (a *Adapter) ReadReg(addr uint16, reg byte) (byte, error) { return i2c.ReadReg(a, addr, reg) }
+
+func (a *Adapter) WriteReg(addr uint16, reg, value byte) error {
+	return i2c.WriteReg(a, addr, reg, value)
+}
+
+func (a *Adapter) Txn(addr uint16, w, r []byte) error { return i2c.Txn(a, addr, w, r) }
+
+// Clock SCL until a target holding SDA low lets go, then issue a stop
+func (a *Adapter) Recover() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := 0; i < 9; i++ {
+		if high, err := a.getSda(); err != nil {
+			return err
+		} else if high {
+			break
+		}
+
+		if err := a.setScl(false); err != nil {
+			return err
+		}
+		a.delay()
+
+		if err := a.sclHigh(); err != nil {
+			return err
+		}
+		a.delay()
+	}
+
+	if err := a.stop(); err != nil {
+		return err
+	}
+
+	if high, err := a.getSda(); err != nil {
+		return err
+	} else if !high {
+		return ErrBusStuck
+	}
+
+	return nil
+}
+
+// Perform the messages as one combined transfer, with the same semantics as
+// i2c.Device. MsgNoStart is honoured only where the direction does not
+// change, so that the i2c.ReadReg idiom still issues a repeated start.
+func (a *Adapter) Rdwr(msgs []i2c.Msg) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err = a.start(); err != nil {
+		return
+	}
+
+	defer func() {
+		if serr := a.stop(); err == nil {
+			err = serr
+		}
+	}()
+
+	for i := range msgs {
+		var (
+			msg     = &msgs[i]
+			nakOk   = msg.Flags&i2c.MsgIgnoreNak != 0
+			address = true
+		)
+
+		if i > 0 {
+			var (
+				prev    = &msgs[i-1]
+				sameDir = (prev.Flags^msg.Flags)&i2c.MsgRead == 0
+			)
+
+			switch {
+			case msg.Flags&i2c.MsgNoStart != 0 && sameDir && prev.Addr == msg.Addr:
+				address = false
+
+			case prev.Flags&i2c.MsgSstop != 0:
+				if err = a.stop(); err == nil {
+					err = a.start()
+				}
+
+			default:
+				err = a.repeatedStart()
+			}
+
+			if err != nil {
+				return
+			}
+		}
+
+		if address {
+			if err = a.address(msg, nakOk); err != nil {
+				return
+			}
+		}
+
+		if msg.Flags&i2c.MsgRead != 0 {
+			err = a.readMsg(msg)
+		} else {
+			err = a.writeMsg(msg, nakOk)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// Send the address, with the 10-bit scheme of a write header, address low
+// byte, then for reads a repeated start and read header
+func (a *Adapter) address(msg *i2c.Msg, nakOk bool) error {
+	var rw byte
+	if msg.Flags&i2c.MsgRead != 0 {
+		rw = 1
+	}
+	if msg.Flags&i2c.MsgRevDirAddr != 0 {
+		rw ^= 1
+	}
+
+	var send = func(b byte) error {
+		ack, err := a.writeByte(b)
+		if err != nil {
+			return err
+		}
+		if !ack && !nakOk {
+			return ErrNak
+		}
+		return nil
+	}
+
+	if msg.Flags&i2c.MsgTen == 0 {
+		return send(byte(msg.Addr&0x7f)<<1 | rw)
+	}
+
+	var header = 0xf0 | byte(msg.Addr>>7)&0x06
+
+	if err := send(header); err != nil {
+		return err
+	}
+
+	if err := send(byte(msg.Addr)); err != nil {
+		return err
+	}
+
+	if rw == 1 {
+		if err := a.repeatedStart(); err != nil {
+			return err
+		}
+
+		return send(header | 1)
+	}
+
+	return nil
+}
+
+func (a *Adapter) writeMsg(msg *i2c.Msg, nakOk bool) error {
+	for _, b := range msg.Buf {
+		ack, err := a.writeByte(b)
+		if err != nil {
+			return err
+		}
+
+		if !ack && !nakOk {
+			return ErrDataNak
+		}
+	}
+
+	return nil
+}
+
+// Read into the message buffer, acknowledging every byte but the last.
+// MsgNoReadAck omits the acknowledge bit entirely.
+func (a *Adapter) readMsg(msg *i2c.Msg) error {
+	var noAck = msg.Flags&i2c.MsgNoReadAck != 0
+
+	var read = func(last bool) (byte, error) {
+		b, err := a.readBits()
+		if err == nil && !noAck {
+			err = a.writeBit(last)
+		}
+		return b, err
+	}
+
+	if msg.Flags&i2c.MsgRecvLen == 0 {
+		for i := range msg.Buf {
+			var err error
+			if msg.Buf[i], err = read(i == len(msg.Buf)-1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// SMBus block read, where the first byte gives the number that follow. The
+	// buffer is resliced to the length received, within its capacity.
+	if cap(msg.Buf) < 1 {
+		return unix.EINVAL
+	}
+
+	count, err := read(false)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 || count > blockMax || int(count)+1 > cap(msg.Buf) {
+		// The target expects more data, so NAK a byte to end the transfer
+		read(true)
+		return unix.EPROTO
+	}
+
+	msg.Buf = msg.Buf[:1+int(count)]
+	msg.Buf[0] = count
+
+	for i := 1; i < len(msg.Buf); i++ {
+		if msg.Buf[i], err = read(i == len(msg.Buf)-1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Adapter) String() string {
+	return fmt.Sprintf("bitbang(sda=%d, scl=%d)", a.offsets[sdaLine], a.offsets[sclLine])
+}
@@ -0,0 +1,182 @@
+package bitbang
+
+import (
+	"time"
+)
+
+// Bit level signalling. SCL is low between bits, and releasing a line lets
+// the pull-up, or another device, determine its level.
+
+func (a *Adapter) delay() {
+	if a.HalfPeriod > 0 {
+		time.Sleep(a.HalfPeriod)
+	}
+}
+
+func (a *Adapter) setSda(high bool) error { return a.lines.SetValue(sdaLine, high) }
+
+func (a *Adapter) setScl(high bool) error { return a.lines.SetValue(sclLine, high) }
+
+func (a *Adapter) getSda() (bool, error) { return a.lines.Value(sdaLine) }
+
+func (a *Adapter) getScl() (bool, error) { return a.lines.Value(sclLine) }
+
+// Release SCL and wait for any target stretching the clock to let go
+func (a *Adapter) sclHigh() error {
+	if err := a.setScl(true); err != nil {
+		return err
+	}
+
+	var deadline = time.Now().Add(a.StretchTimeout)
+
+	for {
+		high, err := a.getScl()
+		if err != nil {
+			return err
+		}
+
+		if high {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return ErrStretchTimeout
+		}
+
+		time.Sleep(a.HalfPeriod / 4)
+	}
+}
+
+// SDA falls while SCL is high
+func (a *Adapter) start() error {
+	if err := a.setSda(true); err != nil {
+		return err
+	}
+
+	if err := a.sclHigh(); err != nil {
+		return err
+	}
+
+	if high, err := a.getSda(); err != nil {
+		return err
+	} else if !high {
+		return ErrArbitration
+	}
+
+	a.delay()
+
+	if err := a.setSda(false); err != nil {
+		return err
+	}
+	a.delay()
+
+	return a.setScl(false)
+}
+
+// From the middle of a transfer, with SCL low
+func (a *Adapter) repeatedStart() error {
+	if err := a.setSda(true); err != nil {
+		return err
+	}
+	a.delay()
+
+	return a.start()
+}
+
+// SDA rises while SCL is high. SCL is driven low first, in case a failed
+// transfer left it released.
+func (a *Adapter) stop() error {
+	if err := a.setScl(false); err != nil {
+		return err
+	}
+
+	if err := a.setSda(false); err != nil {
+		return err
+	}
+	a.delay()
+
+	if err := a.sclHigh(); err != nil {
+		return err
+	}
+	a.delay()
+
+	if err := a.setSda(true); err != nil {
+		return err
+	}
+	a.delay()
+
+	return nil
+}
+
+func (a *Adapter) writeBit(high bool) error {
+	if err := a.setSda(high); err != nil {
+		return err
+	}
+	a.delay()
+
+	if err := a.sclHigh(); err != nil {
+		return err
+	}
+
+	// Someone else driving SDA low while we released it
+	if high {
+		if v, err := a.getSda(); err != nil {
+			return err
+		} else if !v {
+			return ErrArbitration
+		}
+	}
+
+	a.delay()
+
+	return a.setScl(false)
+}
+
+func (a *Adapter) readBit() (bool, error) {
+	if err := a.setSda(true); err != nil {
+		return false, err
+	}
+	a.delay()
+
+	if err := a.sclHigh(); err != nil {
+		return false, err
+	}
+
+	v, err := a.getSda()
+	if err != nil {
+		return false, err
+	}
+
+	a.delay()
+
+	return v, a.setScl(false)
+}
+
+// Write a byte MSB first, returning whether the target acknowledged it
+func (a *Adapter) writeByte(b byte) (ack bool, err error) {
+	for i := 7; i >= 0; i-- {
+		if err = a.writeBit(b&(1<<i) != 0); err != nil {
+			return
+		}
+	}
+
+	nak, err := a.readBit()
+	return !nak, err
+}
+
+// Read a byte MSB first, without the acknowledge bit
+func (a *Adapter) readBits() (b byte, err error) {
+	for i := 0; i < 8; i++ {
+		v, err := a.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		b <<= 1
+		if v {
+			b |= 1
+		}
+	}
+
+	return
+}
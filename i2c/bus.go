@@ -0,0 +1,93 @@
+package i2c
+
+// Bus is implemented by Device and by other adapters, such as USB bridges,
+// that can perform the same kinds of transfers
+type Bus interface {
+	Rdwr(msgs []Msg) error
+	ReadReg(addr uint16, reg byte) (byte, error)
+	WriteReg(addr uint16, reg, value byte) error
+	Txn(addr uint16, w, r []byte) error
+	Functionality() uintptr
+	Close() error
+}
+
+var _ Bus = (*Device)(nil)
+
+// Rdwrer
+type Rdwrer interface {
+	Rdwr(msgs []Msg) error
+}
+
+// Adapter functionality, as reported by `I2C_FUNCS`
+const (
+	FuncI2c                 = 0x00000001
+	Func10BitAddr           = 0x00000002
+	FuncProtocolMangling    = 0x00000004
+	FuncSmbusPec            = 0x00000008
+	FuncNoStart             = 0x00000010
+	FuncSlave               = 0x00000020
+	FuncSmbusBlockProcCall  = 0x00008000
+	FuncSmbusQuick          = 0x00010000
+	FuncSmbusReadByte       = 0x00020000
+	FuncSmbusWriteByte      = 0x00040000
+	FuncSmbusReadByteData   = 0x00080000
+	FuncSmbusWriteByteData  = 0x00100000
+	FuncSmbusReadWordData   = 0x00200000
+	FuncSmbusWriteWordData  = 0x00400000
+	FuncSmbusProcCall       = 0x00800000
+	FuncSmbusReadBlockData  = 0x01000000
+	FuncSmbusWriteBlockData = 0x02000000
+	FuncSmbusReadI2cBlock   = 0x04000000
+	FuncSmbusWriteI2cBlock  = 0x08000000
+	FuncSmbusHostNotify     = 0x10000000
+)
+
+// Register and transaction helpers shared by all Bus implementations, in
+// terms of Rdwr
+
+func ReadReg(rw Rdwrer, addr uint16, reg byte) (byte, error) {
+	var (
+		outbuf = [1]byte{reg}
+		inbuf  [1]byte
+		msgs   = [2]Msg{
+			{Addr: addr, Flags: 0, Buf: outbuf[:]},
+			{Addr: addr, Flags: MsgRead | MsgNoStart, Buf: inbuf[:]},
+		}
+	)
+
+	if err := rw.Rdwr(msgs[:]); err != nil {
+		return 0, err
+	}
+
+	return inbuf[0], nil
+}
+
+func WriteReg(rw Rdwrer, addr uint16, reg, value byte) error {
+	var (
+		outbuf = [2]byte{reg, value}
+		msgs   = [1]Msg{{Addr: addr, Flags: 0, Buf: outbuf[:]}}
+	)
+
+	return rw.Rdwr(msgs[:])
+}
+
+func Txn(rw Rdwrer, addr uint16, w, r []byte) error {
+	if w == nil {
+		var one [1]byte
+		w = one[:]
+	}
+
+	var (
+		raw = [2]Msg{
+			{Addr: addr, Flags: 0, Buf: w},
+			{Addr: addr, Flags: MsgRead | MsgNoStart, Buf: r},
+		}
+		msgs = raw[:2]
+	)
+
+	if r == nil {
+		msgs = raw[:1]
+	}
+
+	return rw.Rdwr(msgs)
+}
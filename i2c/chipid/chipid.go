@@ -0,0 +1,200 @@
+package chipid
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// Identify devices on an I2C bus in the manner of `sensors-detect`, using
+// only register reads. Reading a register does involve writing its address
+// to the device first, which is harmless for register based chips; probes
+// of chips without a register pointer, such as GPIO expanders, only ever
+// read.
+
+const (
+	FirstAddr = 0x08
+	LastAddr  = 0x77
+)
+
+// Chip
+type Chip struct {
+	Name string
+
+	// Device name to instantiate via `new_device`
+	Driver string
+
+	Addrs []uint16
+
+	// Return a confidence from 0 (not this chip) to 100
+	Probe func(p *Prober) int
+}
+
+// Candidate
+type Candidate struct {
+	Chip       *Chip
+	Addr       uint16
+	Confidence int
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("%s at 0x%02x (%d%%, driver %s)", c.Chip.Name, c.Addr, c.Confidence, c.Chip.Driver)
+}
+
+// The line to write to the adapter's `new_device` sysfs file
+func (c Candidate) NewDevice() string { return fmt.Sprintf("%s 0x%02x", c.Chip.Driver, c.Addr) }
+
+// Instantiate the candidate's kernel driver on adapter `i2c-<adapter>`
+func (c Candidate) Instantiate(adapter int) error {
+	var path = filepath.Join("/sys/bus/i2c/devices", fmt.Sprintf("i2c-%d", adapter), "new_device")
+	return os.WriteFile(path, []byte(c.NewDevice()), 0200)
+}
+
+// Prober performs and caches the reads of a single device's registers
+type Prober struct {
+	dev   i2c.Bus
+	addr  uint16
+	cache map[probeKey]uint16
+	fail  map[probeKey]bool
+}
+
+type probeKey struct {
+	reg   int
+	width int
+}
+
+func newProber(dev i2c.Bus, addr uint16) *Prober {
+	return &Prober{
+		dev:   dev,
+		addr:  addr,
+		cache: make(map[probeKey]uint16),
+		fail:  make(map[probeKey]bool),
+	}
+}
+
+func (p *Prober) Addr() uint16 { return p.addr }
+
+func (p *Prober) read(reg, width int) (uint16, bool) {
+	var key = probeKey{reg, width}
+
+	if v, ok := p.cache[key]; ok {
+		return v, true
+	}
+
+	if p.fail[key] {
+		return 0, false
+	}
+
+	var (
+		buf [2]byte
+		out = [1]byte{byte(reg)}
+		raw = [2]i2c.Msg{
+			{Addr: p.addr, Flags: 0, Buf: out[:]},
+			{Addr: p.addr, Flags: i2c.MsgRead, Buf: buf[:width]},
+		}
+		msgs = raw[:]
+	)
+
+	// Plain read without a register pointer
+	if reg < 0 {
+		msgs = raw[1:]
+	}
+
+	if err := p.dev.Rdwr(msgs); err != nil {
+		p.fail[key] = true
+		return 0, false
+	}
+
+	var v = uint16(buf[0])
+	if width == 2 {
+		v = v<<8 | uint16(buf[1])
+	}
+
+	p.cache[key] = v
+	return v, true
+}
+
+// Read a byte without writing a register address first
+func (p *Prober) Byte() (byte, bool) {
+	v, ok := p.read(-1, 1)
+	return byte(v), ok
+}
+
+func (p *Prober) Reg8(reg byte) (byte, bool) {
+	v, ok := p.read(int(reg), 1)
+	return byte(v), ok
+}
+
+// Big endian 16-bit register
+func (p *Prober) Reg16(reg byte) (uint16, bool) { return p.read(int(reg), 2) }
+
+func (p *Prober) Reg8Is(reg, want byte) bool {
+	v, ok := p.Reg8(reg)
+	return ok && v == want
+}
+
+func (p *Prober) Reg16Is(reg byte, want uint16) bool {
+	v, ok := p.Reg16(reg)
+	return ok && v == want
+}
+
+// Check whether anything acknowledges a read at `addr`
+func Responds(dev i2c.Bus, addr uint16) bool {
+	var (
+		buf  [1]byte
+		msgs = [1]i2c.Msg{{Addr: addr, Flags: i2c.MsgRead, Buf: buf[:]}}
+	)
+
+	return dev.Rdwr(msgs[:]) == nil
+}
+
+// Find all responding addresses in the usual 7-bit range
+func Scan(dev i2c.Bus) (addrs []uint16) {
+	for addr := uint16(FirstAddr); addr <= LastAddr; addr++ {
+		if Responds(dev, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return
+}
+
+// Identify the device at `addr` against `db`, or the built-in Database if
+// nil, returning candidates with the most likely first
+func Identify(dev i2c.Bus, addr uint16, db []Chip) (cands []Candidate) {
+	if db == nil {
+		db = Database
+	}
+
+	var p = newProber(dev, addr)
+
+	for i := range db {
+		var chip = &db[i]
+
+		if !slices.Contains(chip.Addrs, addr) {
+			continue
+		}
+
+		if conf := chip.Probe(p); conf > 0 {
+			cands = append(cands, Candidate{Chip: chip, Addr: addr, Confidence: min(conf, 100)})
+		}
+	}
+
+	slices.SortStableFunc(cands, func(a, b Candidate) int { return b.Confidence - a.Confidence })
+
+	return
+}
+
+// Scan the bus and identify every responding address
+func Detect(dev i2c.Bus, db []Chip) map[uint16][]Candidate {
+	var found = make(map[uint16][]Candidate)
+
+	for _, addr := range Scan(dev) {
+		found[addr] = Identify(dev, addr, db)
+	}
+
+	return found
+}
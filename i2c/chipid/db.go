@@ -0,0 +1,296 @@
+package chipid
+
+func addrRange(first, last uint16) (addrs []uint16) {
+	for a := first; a <= last; a++ {
+		addrs = append(addrs, a)
+	}
+	return
+}
+
+func isBcd(v byte, limit byte) bool { return v&0x0f <= 9 && v>>4 <= 9 && v <= limit }
+
+// Match a chip ID register, such as WHO_AM_I
+func idReg(reg, want byte, conf int) func(p *Prober) int {
+	return func(p *Prober) int {
+		if p.Reg8Is(reg, want) {
+			return conf
+		}
+		return 0
+	}
+}
+
+// Database of common chips, loosely ordered by how distinctive their probes are
+var Database = []Chip{
+	{
+		Name:   "Bosch BME280",
+		Driver: "bme280",
+		Addrs:  []uint16{0x76, 0x77},
+		Probe:  idReg(0xd0, 0x60, 95),
+	},
+	{
+		Name:   "Bosch BMP280",
+		Driver: "bmp280",
+		Addrs:  []uint16{0x76, 0x77},
+		Probe: func(p *Prober) int {
+			if v, ok := p.Reg8(0xd0); ok && v >= 0x56 && v <= 0x58 {
+				return 95
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "Bosch BMP180",
+		Driver: "bmp180",
+		Addrs:  []uint16{0x77},
+		Probe:  idReg(0xd0, 0x55, 95),
+	},
+	{
+		Name:   "Bosch BME680",
+		Driver: "bme680",
+		Addrs:  []uint16{0x76, 0x77},
+		Probe:  idReg(0xd0, 0x61, 95),
+	},
+	{
+		Name:   "InvenSense MPU-6050",
+		Driver: "mpu6050",
+		Addrs:  []uint16{0x68, 0x69},
+		Probe:  idReg(0x75, 0x68, 90),
+	},
+	{
+		Name:   "InvenSense MPU-6500",
+		Driver: "mpu6500",
+		Addrs:  []uint16{0x68, 0x69},
+		Probe:  idReg(0x75, 0x70, 90),
+	},
+	{
+		Name:   "InvenSense MPU-9250",
+		Driver: "mpu9250",
+		Addrs:  []uint16{0x68, 0x69},
+		Probe:  idReg(0x75, 0x71, 90),
+	},
+	{
+		Name:   "ST LIS3DH",
+		Driver: "lis3dh",
+		Addrs:  []uint16{0x18, 0x19},
+		Probe:  idReg(0x0f, 0x33, 90),
+	},
+	{
+		Name:   "ST LSM6DS3",
+		Driver: "lsm6ds3",
+		Addrs:  []uint16{0x6a, 0x6b},
+		Probe:  idReg(0x0f, 0x69, 90),
+	},
+	{
+		Name:   "ST LSM6DSL",
+		Driver: "lsm6dsl",
+		Addrs:  []uint16{0x6a, 0x6b},
+		Probe:  idReg(0x0f, 0x6a, 90),
+	},
+	{
+		Name:   "ST LSM6DSO",
+		Driver: "lsm6dso",
+		Addrs:  []uint16{0x6a, 0x6b},
+		Probe:  idReg(0x0f, 0x6c, 90),
+	},
+	{
+		Name:   "Analog Devices ADXL345",
+		Driver: "adxl345",
+		Addrs:  []uint16{0x1d, 0x53},
+		Probe:  idReg(0x00, 0xe5, 95),
+	},
+	{
+		Name:   "Honeywell HMC5883L",
+		Driver: "hmc5883l",
+		Addrs:  []uint16{0x1e},
+		Probe: func(p *Prober) int {
+			if p.Reg8Is(10, 'H') && p.Reg8Is(11, '4') && p.Reg8Is(12, '3') {
+				return 98
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "Microchip MCP9808",
+		Driver: "jc42",
+		Addrs:  addrRange(0x18, 0x1f),
+		Probe: func(p *Prober) int {
+			if !p.Reg16Is(0x06, 0x0054) {
+				return 0
+			}
+			if v, ok := p.Reg16(0x07); ok && v>>8 == 0x04 {
+				return 95
+			}
+			return 60
+		},
+	},
+	{
+		Name:   "TI TMP117",
+		Driver: "tmp117",
+		Addrs:  addrRange(0x48, 0x4b),
+		Probe: func(p *Prober) int {
+			if v, ok := p.Reg16(0x0f); ok && v&0x0fff == 0x0117 {
+				return 95
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "TI INA226",
+		Driver: "ina226",
+		Addrs:  addrRange(0x40, 0x4f),
+		Probe: func(p *Prober) int {
+			if p.Reg16Is(0xfe, 0x5449) && p.Reg16Is(0xff, 0x2260) {
+				return 98
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "TI INA219",
+		Driver: "ina219",
+		Addrs:  addrRange(0x40, 0x4f),
+		Probe: func(p *Prober) int {
+			// Power-on configuration, and INA226 ID registers absent
+			if !p.Reg16Is(0x00, 0x399f) {
+				return 0
+			}
+			if p.Reg16Is(0xfe, 0x5449) {
+				return 0
+			}
+			return 70
+		},
+	},
+	{
+		Name:   "NXP PCA9685",
+		Driver: "pca9685",
+		Addrs:  addrRange(0x40, 0x6f), // 0x70 onwards is where muxes live
+		Probe: func(p *Prober) int {
+			var conf = 0
+			if v, ok := p.Reg8(0x00); ok && v&0x0e == 0 {
+				conf += 20
+			}
+			if p.Reg8Is(0x01, 0x04) {
+				conf += 20
+			}
+			if v, ok := p.Reg8(0xfe); ok && v >= 0x03 {
+				conf += 20
+				if v == 0x1e {
+					conf += 20
+				}
+			}
+			if conf < 60 {
+				return 0
+			}
+			return conf
+		},
+	},
+	{
+		Name:   "TI ADS1115",
+		Driver: "ads1115",
+		Addrs:  addrRange(0x48, 0x4b),
+		Probe: func(p *Prober) int {
+			if !p.Reg16Is(0x01, 0x8583) {
+				return 0
+			}
+			if p.Reg16Is(0x02, 0x8000) && p.Reg16Is(0x03, 0x7fff) {
+				return 90
+			}
+			return 60
+		},
+	},
+	{
+		Name:   "TI TMP102",
+		Driver: "tmp102",
+		Addrs:  addrRange(0x48, 0x4b),
+		Probe: func(p *Prober) int {
+			if v, ok := p.Reg16(0x01); ok && v&0x7cff == 0x60a0 {
+				return 60
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "National LM75",
+		Driver: "lm75",
+		Addrs:  addrRange(0x48, 0x4f),
+		Probe: func(p *Prober) int {
+			// Unused bits of the configuration and limit registers read 0
+			if v, ok := p.Reg8(0x01); !ok || v&0xe0 != 0 {
+				return 0
+			}
+
+			hyst, ok1 := p.Reg16(0x02)
+			os, ok2 := p.Reg16(0x03)
+			if !ok1 || !ok2 || hyst&0x7f != 0 || os&0x7f != 0 {
+				return 0
+			}
+
+			// Power-on defaults of 75 and 80 degrees
+			if hyst == 0x4b00 && os == 0x5000 {
+				return 50
+			}
+			return 25
+		},
+	},
+	{
+		Name:   "Maxim DS3231",
+		Driver: "ds3231",
+		Addrs:  []uint16{0x68},
+		Probe: func(p *Prober) int {
+			sec, ok1 := p.Reg8(0x00)
+			minute, ok2 := p.Reg8(0x01)
+			status, ok3 := p.Reg8(0x0f)
+			if !ok1 || !ok2 || !ok3 || !isBcd(sec, 0x59) || !isBcd(minute, 0x59) || status&0x70 != 0 {
+				return 0
+			}
+			return 50
+		},
+	},
+	{
+		Name:   "Maxim DS1307",
+		Driver: "ds1307",
+		Addrs:  []uint16{0x68},
+		Probe: func(p *Prober) int {
+			sec, ok1 := p.Reg8(0x00)
+			minute, ok2 := p.Reg8(0x01)
+			ctrl, ok3 := p.Reg8(0x07)
+			if !ok1 || !ok2 || !ok3 || !isBcd(sec&0x7f, 0x59) || !isBcd(minute, 0x59) || ctrl&0x6c != 0 {
+				return 0
+			}
+			return 30
+		},
+	},
+	{
+		Name:   "Maxim MAX17048",
+		Driver: "max17048",
+		Addrs:  []uint16{0x36},
+		Probe: func(p *Prober) int {
+			if v, ok := p.Reg16(0x08); ok && v&0xfff0 == 0x0010 {
+				return 70
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "24Cxx EEPROM",
+		Driver: "24c02",
+		Addrs:  addrRange(0x50, 0x57),
+		Probe: func(p *Prober) int {
+			if _, ok := p.Reg8(0x00); ok {
+				return 10
+			}
+			return 0
+		},
+	},
+	{
+		Name:   "NXP PCF8574",
+		Driver: "pcf8574",
+		Addrs:  append(addrRange(0x20, 0x27), addrRange(0x38, 0x3f)...),
+		Probe: func(p *Prober) int {
+			if _, ok := p.Byte(); ok {
+				return 10
+			}
+			return 0
+		},
+	},
+}
@@ -0,0 +1,213 @@
+package cp2112
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// Silicon Labs CP2112 USB HID to SMBus/I2C bridge, driven through hidraw
+// rather than the kernel `hid-cp2112` driver. See Silicon Labs AN495.
+
+const (
+	VendorId  = 0x10c4
+	ProductId = 0xea90
+
+	HidrawGlob = "/sys/class/hidraw/hidraw*"
+
+	DefaultTimeout = time.Second
+)
+
+var (
+	ErrTimeout     = errors.New("cp2112: transfer timed out")
+	ErrUnsupported = errors.New("cp2112: unsupported message sequence")
+	ErrNotCp2112   = errors.New("cp2112: not a CP2112 device")
+)
+
+// Device
+type Device struct {
+	f       *os.File
+	Timeout time.Duration
+}
+
+var _ i2c.Bus = (*Device)(nil)
+
+// Find hidraw device nodes belonging to CP2112 bridges
+func FindDevices() []string {
+	var (
+		paths []string
+		found []string
+		err   error
+	)
+
+	if paths, err = filepath.Glob(HidrawGlob); err != nil {
+		return nil
+	}
+
+	var want = fmt.Sprintf("HID_ID=%04X:%08X:%08X", 0x0003, VendorId, ProductId)
+
+	for _, path := range paths {
+		uevent, err := os.ReadFile(filepath.Join(path, "device", "uevent"))
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(uevent), "\n") {
+			if line == want {
+				found = append(found, filepath.Join("/dev", filepath.Base(path)))
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+func OpenDevice(name string) (dev *Device, err error) {
+	var f *os.File
+
+	defer func() {
+		if err != nil && f != nil {
+			f.Close()
+		}
+	}()
+
+	if !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "./") {
+		name = filepath.Join("/dev", name)
+	}
+
+	f, err = os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	var info *unix.HIDRawDevInfo
+	if cerr := rc.Control(func(fd uintptr) {
+		info, err = unix.IoctlHIDGetRawInfo(int(fd))
+	}); cerr != nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return
+	}
+
+	if uint16(info.Vendor) != VendorId || uint16(info.Product) != ProductId {
+		err = fmt.Errorf("%w: %s is %04x:%04x", ErrNotCp2112, name, uint16(info.Vendor), uint16(info.Product))
+		return
+	}
+
+	dev = &Device{
+		f:       f,
+		Timeout: DefaultTimeout,
+	}
+
+	// Abandon anything left over from a previous user
+	if err = dev.cancel(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (dev *Device) Close() error { return dev.f.Close() }
+
+// The bridge handles plain I2C transfers, and SMBus transactions through
+// them, but not 10-bit addressing or protocol mangling
+func (dev *Device) Functionality() uintptr {
+	return i2c.FuncI2c |
+		i2c.FuncSmbusReadByte | i2c.FuncSmbusWriteByte |
+		i2c.FuncSmbusReadByteData | i2c.FuncSmbusWriteByteData |
+		i2c.FuncSmbusReadWordData | i2c.FuncSmbusWriteWordData |
+		i2c.FuncSmbusReadI2cBlock | i2c.FuncSmbusWriteI2cBlock
+}
+
+func (dev *Device) ReadReg(addr uint16, reg byte) (byte, error) { return i2c.ReadReg(dev, addr, reg) }
+
+func (dev *Device) WriteReg(addr uint16, reg, value byte) error {
+	return i2c.WriteReg(dev, addr, reg, value)
+}
+
+func (dev *Device) Txn(addr uint16, w, r []byte) error { return i2c.Txn(dev, addr, w, r) }
+
+// Perform each message as a separate bridge transfer, except that a write
+// of up to 16 bytes followed by a read of the same address is issued as a
+// single write-read with a repeated start. Writes following writes cannot be
+// joined, so `MsgNoStart` is otherwise ignored.
+func (dev *Device) Rdwr(msgs []i2c.Msg) error {
+	for i := 0; i < len(msgs); i++ {
+		var m = &msgs[i]
+
+		if m.Flags&(i2c.MsgTen|i2c.MsgRecvLen) != 0 {
+			return fmt.Errorf("%w: message %d flags 0x%04x", ErrUnsupported, i, m.Flags)
+		}
+
+		if m.Flags&i2c.MsgRead != 0 {
+			if err := dev.read(m.Addr, m.Buf); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if i+1 < len(msgs) {
+			var next = &msgs[i+1]
+
+			if next.Flags&i2c.MsgRead != 0 && next.Addr == m.Addr && len(m.Buf) >= 1 && len(m.Buf) <= maxTargetAddr {
+				if err := dev.writeRead(m.Addr, m.Buf, next.Buf); err != nil {
+					return err
+				}
+
+				i++
+				continue
+			}
+		}
+
+		if err := dev.write(m.Addr, m.Buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Set the SMBus clock speed in Hz
+func (dev *Device) SetClockSpeed(hz int) error {
+	var cfg [smbusConfigLen]byte
+	cfg[0] = reportSmbusConfig
+
+	if err := dev.getFeature(cfg[:]); err != nil {
+		return err
+	}
+
+	cfg[1] = byte(hz >> 24)
+	cfg[2] = byte(hz >> 16)
+	cfg[3] = byte(hz >> 8)
+	cfg[4] = byte(hz)
+
+	return dev.setFeature(cfg[:])
+}
+
+// Part number and firmware version
+func (dev *Device) Version() (part, version byte, err error) {
+	var buf [3]byte
+	buf[0] = reportVersion
+
+	if err = dev.getFeature(buf[:]); err != nil {
+		return
+	}
+
+	return buf[1], buf[2], nil
+}
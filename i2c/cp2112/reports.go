@@ -0,0 +1,224 @@
+package cp2112
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"go.pdmccormick.com/linuxuapi/internal/ioctl"
+	"golang.org/x/sys/unix"
+)
+
+// HID report IDs, AN495 section 4
+const (
+	reportVersion        = 0x05
+	reportSmbusConfig    = 0x06
+	reportReadRequest    = 0x10
+	reportWriteRead      = 0x11
+	reportReadForceSend  = 0x12
+	reportReadResponse   = 0x13
+	reportWrite          = 0x14
+	reportStatusRequest  = 0x15
+	reportStatusResponse = 0x16
+	reportCancel         = 0x17
+
+	reportLen      = 64
+	smbusConfigLen = 14
+
+	maxWrite      = 61
+	maxRead       = 512
+	maxTargetAddr = 16
+)
+
+// Transfer status
+const (
+	status0Idle     = 0x00
+	status0Busy     = 0x01
+	status0Complete = 0x02
+	status0Error    = 0x03
+
+	status1TimeoutNak = 0x00
+	status1TimeoutBus = 0x01
+	status1ArbLost    = 0x02
+)
+
+const pollInterval = time.Millisecond
+
+func hidiocFeature(nr, size uintptr) uintptr {
+	const iocReadWrite = 3
+	return iocReadWrite<<30 | size<<16 | 'H'<<8 | nr
+}
+
+func (dev *Device) ioctl(req uintptr, buf []byte) error {
+	_, err := ioctl.Pointer(dev.f, req, unsafe.Pointer(&buf[0]))
+	return err
+}
+
+func (dev *Device) getFeature(buf []byte) error {
+	return dev.ioctl(hidiocFeature(0x07, uintptr(len(buf))), buf)
+}
+
+func (dev *Device) setFeature(buf []byte) error {
+	return dev.ioctl(hidiocFeature(0x06, uintptr(len(buf))), buf)
+}
+
+func (dev *Device) sendReport(report ...byte) error {
+	var buf [reportLen]byte
+	copy(buf[:], report)
+
+	_, err := dev.f.Write(buf[:])
+	return err
+}
+
+// Wait for an input report with the given ID, discarding any others
+func (dev *Device) recvReport(id byte) ([]byte, error) {
+	var (
+		buf      [reportLen]byte
+		deadline = time.Now().Add(dev.Timeout)
+	)
+
+	if err := dev.f.SetReadDeadline(deadline); err != nil && !errors.Is(err, os.ErrNoDeadline) {
+		return nil, err
+	}
+
+	for {
+		n, err := dev.f.Read(buf[:])
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil, ErrTimeout
+			}
+			return nil, err
+		}
+
+		if n > 0 && buf[0] == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+func (dev *Device) cancel() error { return dev.sendReport(reportCancel, 0x01) }
+
+// Poll the transfer status until the bridge finishes, returning the number
+// of bytes transferred
+func (dev *Device) waitComplete() (int, error) {
+	var deadline = time.Now().Add(dev.Timeout)
+
+	for {
+		if err := dev.sendReport(reportStatusRequest, 0x01); err != nil {
+			return 0, err
+		}
+
+		resp, err := dev.recvReport(reportStatusResponse)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(resp) < 7 {
+			return 0, fmt.Errorf("cp2112: short status response")
+		}
+
+		switch resp[1] {
+		case status0Complete:
+			return int(resp[5])<<8 | int(resp[6]), nil
+
+		case status0Error:
+			switch resp[2] {
+			case status1TimeoutNak:
+				return 0, unix.ENXIO
+			case status1TimeoutBus:
+				return 0, unix.ETIMEDOUT
+			case status1ArbLost:
+				return 0, unix.EAGAIN
+			default:
+				return 0, unix.EIO
+			}
+
+		case status0Idle, status0Busy:
+			if time.Now().After(deadline) {
+				dev.cancel()
+				return 0, ErrTimeout
+			}
+
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+func (dev *Device) write(addr uint16, data []byte) error {
+	if len(data) < 1 || len(data) > maxWrite {
+		return fmt.Errorf("%w: write of %d bytes", ErrUnsupported, len(data))
+	}
+
+	var report = append([]byte{reportWrite, byte(addr << 1), byte(len(data))}, data...)
+
+	if err := dev.sendReport(report...); err != nil {
+		return err
+	}
+
+	_, err := dev.waitComplete()
+	return err
+}
+
+func (dev *Device) read(addr uint16, buf []byte) error {
+	if len(buf) < 1 || len(buf) > maxRead {
+		return fmt.Errorf("%w: read of %d bytes", ErrUnsupported, len(buf))
+	}
+
+	if err := dev.sendReport(reportReadRequest, byte(addr<<1), byte(len(buf)>>8), byte(len(buf))); err != nil {
+		return err
+	}
+
+	return dev.collect(buf)
+}
+
+func (dev *Device) writeRead(addr uint16, w, r []byte) error {
+	if len(r) < 1 || len(r) > maxRead {
+		return fmt.Errorf("%w: read of %d bytes", ErrUnsupported, len(r))
+	}
+
+	var report = append([]byte{reportWriteRead, byte(addr << 1), byte(len(r) >> 8), byte(len(r)), byte(len(w))}, w...)
+
+	if err := dev.sendReport(report...); err != nil {
+		return err
+	}
+
+	return dev.collect(r)
+}
+
+// Wait for a read to finish, then have the bridge send the data
+func (dev *Device) collect(buf []byte) error {
+	n, err := dev.waitComplete()
+	if err != nil {
+		return err
+	}
+
+	if n < len(buf) {
+		return fmt.Errorf("cp2112: short read, %d of %d bytes", n, len(buf))
+	}
+
+	if err := dev.sendReport(reportReadForceSend, byte(len(buf)>>8), byte(len(buf))); err != nil {
+		return err
+	}
+
+	for got := 0; got < len(buf); {
+		resp, err := dev.recvReport(reportReadResponse)
+		if err != nil {
+			return err
+		}
+
+		if len(resp) < 3 {
+			return fmt.Errorf("cp2112: short read response")
+		}
+
+		if resp[1] == status0Error {
+			return unix.EIO
+		}
+
+		var length = min(int(resp[2]), len(resp)-3)
+		got += copy(buf[got:], resp[3:3+length])
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package ddcci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Capabilities
+type Capabilities struct {
+	Raw      string
+	Protocol string
+	Type     string
+	Model    string
+	MccsVer  string
+	Commands []byte
+
+	// Supported VCP codes, with the permitted values for non-continuous
+	// codes, where the display lists them
+	Vcp map[byte][]byte
+
+	// All top-level entries, unparsed
+	Fields map[string]string
+}
+
+func (caps *Capabilities) Supports(code byte) bool {
+	_, ok := caps.Vcp[code]
+	return ok
+}
+
+// Parse a capabilities string such as
+// `(prot(monitor)type(lcd)model(X)cmds(01 02 03)vcp(10 12 60(0F 11))mccs_ver(2.1))`
+func ParseCapabilities(s string) (*Capabilities, error) {
+	var body = strings.TrimSpace(s)
+
+	if strings.HasPrefix(body, "(") {
+		inner, rest, err := splitParens(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("ddcci: trailing capabilities data `%s`", rest)
+		}
+
+		body = inner
+	}
+
+	var caps = Capabilities{
+		Raw:    s,
+		Fields: make(map[string]string),
+	}
+
+	for {
+		body = strings.TrimSpace(body)
+		if body == "" {
+			break
+		}
+
+		var i = strings.IndexByte(body, '(')
+		if i < 0 {
+			return nil, fmt.Errorf("ddcci: capabilities entry `%s` missing value", body)
+		}
+
+		var key = strings.ToLower(strings.TrimSpace(body[:i]))
+
+		value, rest, err := splitParens(body[i:])
+		if err != nil {
+			return nil, err
+		}
+
+		caps.Fields[key] = value
+		body = rest
+	}
+
+	caps.Protocol = caps.Fields["prot"]
+	caps.Type = caps.Fields["type"]
+	caps.Model = caps.Fields["model"]
+	caps.MccsVer = caps.Fields["mccs_ver"]
+
+	if v, ok := caps.Fields["cmds"]; ok {
+		cmds, err := parseCodeList(v)
+		if err != nil {
+			return nil, fmt.Errorf("ddcci: cmds: %w", err)
+		}
+
+		for _, e := range cmds {
+			caps.Commands = append(caps.Commands, e.code)
+		}
+	}
+
+	if v, ok := caps.Fields["vcp"]; ok {
+		vcp, err := parseCodeList(v)
+		if err != nil {
+			return nil, fmt.Errorf("ddcci: vcp: %w", err)
+		}
+
+		caps.Vcp = make(map[byte][]byte, len(vcp))
+		for _, e := range vcp {
+			caps.Vcp[e.code] = e.values
+		}
+	}
+
+	return &caps, nil
+}
+
+// Split a leading parenthesized group from `s`, returning its contents and
+// whatever follows the closing paren
+func splitParens(s string) (inner, rest string, err error) {
+	var depth = 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:], nil
+			}
+		}
+	}
+
+	return "", "", fmt.Errorf("ddcci: unbalanced parentheses in `%s`", s)
+}
+
+type codeEntry struct {
+	code   byte
+	values []byte
+}
+
+// Parse a list of hex codes, each optionally followed by a parenthesized
+// list of hex values, as in `10 12 60(0F 11)`. Some displays omit the
+// spaces between codes, so bare runs of hex digits are split into pairs.
+func parseCodeList(s string) (codes []codeEntry, err error) {
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			break
+		}
+
+		if s[0] == '(' {
+			inner, rest, err := splitParens(s)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(codes) == 0 {
+				return nil, fmt.Errorf("value list `%s` without code", inner)
+			}
+
+			values, err := parseCodeList(inner)
+			if err != nil {
+				return nil, err
+			}
+
+			var last = &codes[len(codes)-1]
+			for _, v := range values {
+				last.values = append(last.values, v.code)
+			}
+
+			s = rest
+			continue
+		}
+
+		var n = strings.IndexAny(s, " (")
+		if n < 0 {
+			n = len(s)
+		}
+
+		var word = s[:n]
+		s = s[n:]
+
+		for len(word) > 0 {
+			var digits = word[:min(2, len(word))]
+			word = word[len(digits):]
+
+			v, err := strconv.ParseUint(digits, 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("bad code `%s`: %w", digits, err)
+			}
+
+			codes = append(codes, codeEntry{code: byte(v)})
+		}
+	}
+
+	return codes, nil
+}
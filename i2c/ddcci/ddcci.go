@@ -0,0 +1,216 @@
+package ddcci
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// See the VESA DDC/CI and MCCS standards, and <https://www.ddcutil.com/>
+
+const (
+	Addr = 0x37
+
+	hostAddr    = 0x51 // source address byte written by the host
+	displayAddr = 0x6e // Addr << 1, seeds the checksum of host messages
+	virtualAddr = 0x50 // seeds the checksum of display replies
+
+	opGetVcp          = 0x01
+	opGetVcpReply     = 0x02
+	opSetVcp          = 0x03
+	opCapabilities    = 0xf3
+	opCapabilitiesRep = 0xe3
+
+	maxFragment = 32
+)
+
+const (
+	DefaultReplyDelay = 40 * time.Millisecond
+	DefaultWriteDelay = 50 * time.Millisecond
+	DefaultRetries    = 3
+)
+
+var (
+	ErrChecksum    = errors.New("ddcci: bad reply checksum")
+	ErrReply       = errors.New("ddcci: malformed reply")
+	ErrUnsupported = errors.New("ddcci: unsupported VCP code")
+	ErrNullMessage = errors.New("ddcci: display replied with null message")
+)
+
+// Monitor
+type Monitor struct {
+	dev  i2c.Bus
+	last time.Time
+
+	// Minimum delay between a request and reading its reply
+	ReplyDelay time.Duration
+
+	// Minimum delay after a message before the next may be sent
+	WriteDelay time.Duration
+
+	Retries int
+}
+
+func NewMonitor(dev i2c.Bus) *Monitor {
+	return &Monitor{
+		dev:        dev,
+		ReplyDelay: DefaultReplyDelay,
+		WriteDelay: DefaultWriteDelay,
+		Retries:    DefaultRetries,
+	}
+}
+
+func checksum(seed byte, b []byte) byte {
+	for _, v := range b {
+		seed ^= v
+	}
+	return seed
+}
+
+func (m *Monitor) pause() {
+	if d := m.WriteDelay - time.Since(m.last); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (m *Monitor) write(payload []byte) error {
+	var buf = make([]byte, 0, len(payload)+3)
+
+	buf = append(buf, hostAddr, 0x80|byte(len(payload)))
+	buf = append(buf, payload...)
+	buf = append(buf, checksum(displayAddr, buf))
+
+	m.pause()
+	defer func() { m.last = time.Now() }()
+
+	var msgs = [1]i2c.Msg{{Addr: Addr, Flags: 0, Buf: buf}}
+	return m.dev.Rdwr(msgs[:])
+}
+
+func (m *Monitor) read(n int) ([]byte, error) {
+	var buf = make([]byte, n+3)
+
+	time.Sleep(m.ReplyDelay)
+	defer func() { m.last = time.Now() }()
+
+	var msgs = [1]i2c.Msg{{Addr: Addr, Flags: i2c.MsgRead, Buf: buf}}
+	if err := m.dev.Rdwr(msgs[:]); err != nil {
+		return nil, err
+	}
+
+	if buf[0] != displayAddr || buf[1]&0x80 == 0 {
+		return nil, ErrReply
+	}
+
+	var length = int(buf[1] &^ 0x80)
+	if length == 0 {
+		return nil, ErrNullMessage
+	}
+
+	if length > n {
+		return nil, fmt.Errorf("%w: length %d exceeds %d", ErrReply, length, n)
+	}
+
+	if checksum(virtualAddr, buf[:length+2]) != buf[length+2] {
+		return nil, ErrChecksum
+	}
+
+	return buf[2 : 2+length], nil
+}
+
+func (m *Monitor) request(payload []byte, n int) (reply []byte, err error) {
+	for i := 0; i <= m.Retries; i++ {
+		if err = m.write(payload); err != nil {
+			continue
+		}
+
+		if reply, err = m.read(n); err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+// VcpValue
+type VcpValue struct {
+	Code    byte
+	Type    byte
+	Current uint16
+	Max     uint16
+}
+
+func (m *Monitor) GetVcp(code byte) (v VcpValue, err error) {
+	reply, err := m.request([]byte{opGetVcp, code}, 8)
+	if err != nil {
+		return
+	}
+
+	if len(reply) != 8 || reply[0] != opGetVcpReply || reply[2] != code {
+		return v, ErrReply
+	}
+
+	if reply[1] != 0 {
+		return v, fmt.Errorf("%w 0x%02x", ErrUnsupported, code)
+	}
+
+	v = VcpValue{
+		Code:    code,
+		Type:    reply[3],
+		Max:     uint16(reply[4])<<8 | uint16(reply[5]),
+		Current: uint16(reply[6])<<8 | uint16(reply[7]),
+	}
+
+	return
+}
+
+func (m *Monitor) SetVcp(code byte, value uint16) error {
+	return m.write([]byte{opSetVcp, code, byte(value >> 8), byte(value)})
+}
+
+// Read the raw capabilities string, which is transferred in fragments
+func (m *Monitor) CapabilitiesString() (string, error) {
+	var caps []byte
+
+	for {
+		var offset = len(caps)
+
+		reply, err := m.request([]byte{opCapabilities, byte(offset >> 8), byte(offset)}, maxFragment+3)
+		if err != nil {
+			return "", err
+		}
+
+		if len(reply) < 3 || reply[0] != opCapabilitiesRep {
+			return "", ErrReply
+		}
+
+		if got := int(reply[1])<<8 | int(reply[2]); got != offset {
+			return "", fmt.Errorf("%w: fragment offset %d, expected %d", ErrReply, got, offset)
+		}
+
+		var data = reply[3:]
+		if len(data) == 0 {
+			break
+		}
+
+		caps = append(caps, data...)
+	}
+
+	// Some displays include the terminating NUL
+	for len(caps) > 0 && caps[len(caps)-1] == 0 {
+		caps = caps[:len(caps)-1]
+	}
+
+	return string(caps), nil
+}
+
+func (m *Monitor) Capabilities() (*Capabilities, error) {
+	s, err := m.CapabilitiesString()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCapabilities(s)
+}
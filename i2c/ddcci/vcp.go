@@ -0,0 +1,77 @@
+package ddcci
+
+// VCP codes, see MCCS
+const (
+	VcpBrightness  = 0x10
+	VcpContrast    = 0x12
+	VcpInputSource = 0x60
+	VcpPowerMode   = 0xd6
+)
+
+// InputSource
+type InputSource uint16
+
+const (
+	InputVga1         InputSource = 0x01
+	InputVga2         InputSource = 0x02
+	InputDvi1         InputSource = 0x03
+	InputDvi2         InputSource = 0x04
+	InputComposite1   InputSource = 0x05
+	InputComposite2   InputSource = 0x06
+	InputSVideo1      InputSource = 0x07
+	InputSVideo2      InputSource = 0x08
+	InputTuner1       InputSource = 0x09
+	InputTuner2       InputSource = 0x0a
+	InputTuner3       InputSource = 0x0b
+	InputComponent1   InputSource = 0x0c
+	InputComponent2   InputSource = 0x0d
+	InputComponent3   InputSource = 0x0e
+	InputDisplayPort1 InputSource = 0x0f
+	InputDisplayPort2 InputSource = 0x10
+	InputHdmi1        InputSource = 0x11
+	InputHdmi2        InputSource = 0x12
+)
+
+// PowerMode
+type PowerMode uint16
+
+const (
+	PowerOn      PowerMode = 0x01
+	PowerStandby PowerMode = 0x02
+	PowerSuspend PowerMode = 0x03
+	PowerOff     PowerMode = 0x04
+	PowerOffHard PowerMode = 0x05
+)
+
+func (m *Monitor) Brightness() (cur, maximum uint16, err error) {
+	v, err := m.GetVcp(VcpBrightness)
+	return v.Current, v.Max, err
+}
+
+func (m *Monitor) SetBrightness(value uint16) error { return m.SetVcp(VcpBrightness, value) }
+
+func (m *Monitor) Contrast() (cur, maximum uint16, err error) {
+	v, err := m.GetVcp(VcpContrast)
+	return v.Current, v.Max, err
+}
+
+func (m *Monitor) SetContrast(value uint16) error { return m.SetVcp(VcpContrast, value) }
+
+// Only the low byte of the input source value is significant
+func (m *Monitor) InputSource() (InputSource, error) {
+	v, err := m.GetVcp(VcpInputSource)
+	return InputSource(v.Current & 0xff), err
+}
+
+func (m *Monitor) SetInputSource(src InputSource) error {
+	return m.SetVcp(VcpInputSource, uint16(src))
+}
+
+func (m *Monitor) PowerMode() (PowerMode, error) {
+	v, err := m.GetVcp(VcpPowerMode)
+	return PowerMode(v.Current), err
+}
+
+func (m *Monitor) SetPowerMode(mode PowerMode) error {
+	return m.SetVcp(VcpPowerMode, uint16(mode))
+}
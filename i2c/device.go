@@ -82,52 +82,15 @@ func (dev *Device) Rdwr(msgs []Msg) error {
 	return dev.ioctl(_I2C_RDWR, uintptr(unsafe.Pointer(&req)))
 }
 
-func (dev *Device) ReadReg(addr uint16, reg byte) (byte, error) {
-	var (
-		outbuf = [1]byte{reg}
-		inbuf  [1]byte
-		msgs   = [2]Msg{
-			{Addr: addr, Flags: 0, Buf: outbuf[:]},
-			{Addr: addr, Flags: MsgRead | MsgNoStart, Buf: inbuf[:]},
-		}
-	)
-
-	if err := dev.Rdwr(msgs[:]); err != nil {
-		return 0, err
-	}
-
-	return inbuf[0], nil
-}
+func (dev *Device) ReadReg(addr uint16, reg byte) (byte, error) { return ReadReg(dev, addr, reg) }
 
 func (dev *Device) WriteReg(addr uint16, reg, value byte) error {
-	var (
-		outbuf = [2]byte{reg, value}
-		msgs   = [1]Msg{{Addr: addr, Flags: 0, Buf: outbuf[:]}}
-	)
-
-	return dev.Rdwr(msgs[:])
+	return WriteReg(dev, addr, reg, value)
 }
 
-func (dev *Device) Txn(addr uint16, w, r []byte) error {
-	if w == nil {
-		var one [1]byte
-		w = one[:]
-	}
+func (dev *Device) Txn(addr uint16, w, r []byte) error { return Txn(dev, addr, w, r) }
 
-	var (
-		raw = [2]Msg{
-			{Addr: addr, Flags: 0, Buf: w},
-			{Addr: addr, Flags: MsgRead | MsgNoStart, Buf: r},
-		}
-		msgs = raw[:2]
-	)
-
-	if r == nil {
-		msgs = raw[:1]
-	}
-
-	return dev.Rdwr(msgs)
-}
+func (dev *Device) Functionality() uintptr { return dev.Funcs }
 
 // Msg
 type Msg struct {
@@ -0,0 +1,290 @@
+package edid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Base
+type Base struct {
+	Manufacturer string
+	ProductCode  uint16
+	SerialNumber uint32
+	Week         int
+	Year         int
+	Version      int
+	Revision     int
+
+	Digital      bool
+	BitDepth     int // digital inputs only, 0 if undefined
+	Interface    int // digital inputs only
+	WidthCm      int
+	HeightCm     int
+	Gamma        float64
+	FeatureFlags byte
+
+	EstablishedTimings []Mode
+	StandardTimings    []Mode
+	DetailedTimings    []DetailedTiming
+
+	// From display descriptors
+	Name         string
+	SerialString string
+	Text         []string
+	RangeLimits  *RangeLimits
+}
+
+// Mode
+type Mode struct {
+	Width      int
+	Height     int
+	Refresh    int // Hz
+	Interlaced bool
+}
+
+func (m Mode) String() string {
+	var scan = "p"
+	if m.Interlaced {
+		scan = "i"
+	}
+	return fmt.Sprintf("%dx%d%s%d", m.Width, m.Height, scan, m.Refresh)
+}
+
+// DetailedTiming
+type DetailedTiming struct {
+	PixelClock    int // kHz
+	HActive       int
+	HBlank        int
+	HSyncOffset   int
+	HSyncWidth    int
+	VActive       int
+	VBlank        int
+	VSyncOffset   int
+	VSyncWidth    int
+	WidthMm       int
+	HeightMm      int
+	Interlaced    bool
+	HSyncPositive bool
+	VSyncPositive bool
+}
+
+func (t *DetailedTiming) Mode() Mode {
+	var (
+		m = Mode{
+			Width:      t.HActive,
+			Height:     t.VActive,
+			Interlaced: t.Interlaced,
+		}
+		total = (t.HActive + t.HBlank) * (t.VActive + t.VBlank)
+	)
+
+	if t.Interlaced {
+		m.Height *= 2
+	}
+
+	if total > 0 {
+		m.Refresh = int((int64(t.PixelClock)*1000 + int64(total)/2) / int64(total))
+	}
+
+	return m
+}
+
+// RangeLimits
+type RangeLimits struct {
+	MinVRate      int // Hz
+	MaxVRate      int // Hz
+	MinHRate      int // kHz
+	MaxHRate      int // kHz
+	MaxPixelClock int // MHz
+}
+
+// Display descriptor tags
+const (
+	descSerial      = 0xff
+	descText        = 0xfe
+	descRangeLimits = 0xfd
+	descName        = 0xfc
+)
+
+var establishedTimings = [...]Mode{
+	// Byte 35, bit 7 first
+	{720, 400, 70, false},
+	{720, 400, 88, false},
+	{640, 480, 60, false},
+	{640, 480, 67, false},
+	{640, 480, 72, false},
+	{640, 480, 75, false},
+	{800, 600, 56, false},
+	{800, 600, 60, false},
+
+	// Byte 36
+	{800, 600, 72, false},
+	{800, 600, 75, false},
+	{832, 624, 75, false},
+	{1024, 768, 87, true},
+	{1024, 768, 60, false},
+	{1024, 768, 70, false},
+	{1024, 768, 75, false},
+	{1280, 1024, 75, false},
+
+	// Byte 37, bit 7
+	{1152, 870, 75, false},
+}
+
+func (b *Base) decode(block []byte) {
+	var mfg = binary.BigEndian.Uint16(block[8:10])
+
+	b.Manufacturer = string([]byte{
+		'@' + byte(mfg>>10&0x1f),
+		'@' + byte(mfg>>5&0x1f),
+		'@' + byte(mfg&0x1f),
+	})
+	b.ProductCode = binary.LittleEndian.Uint16(block[10:12])
+	b.SerialNumber = binary.LittleEndian.Uint32(block[12:16])
+	b.Week = int(block[16])
+	b.Year = int(block[17]) + 1990
+	b.Version = int(block[18])
+	b.Revision = int(block[19])
+
+	var input = block[20]
+	b.Digital = input&0x80 != 0
+	if b.Digital && b.Version == 1 && b.Revision >= 4 {
+		if depth := int(input>>4) & 0x07; depth != 0 && depth != 7 {
+			b.BitDepth = 4 + 2*depth
+		}
+		b.Interface = int(input & 0x0f)
+	}
+
+	b.WidthCm = int(block[21])
+	b.HeightCm = int(block[22])
+	if block[23] != 0xff {
+		b.Gamma = float64(int(block[23])+100) / 100
+	}
+	b.FeatureFlags = block[24]
+
+	var established = uint32(block[35])<<16 | uint32(block[36])<<8 | uint32(block[37])
+	for i, m := range establishedTimings {
+		if established&(1<<(23-i)) != 0 {
+			b.EstablishedTimings = append(b.EstablishedTimings, m)
+		}
+	}
+
+	for i := 38; i < 54; i += 2 {
+		if m, ok := decodeStandardTiming(block[i], block[i+1], b.Version, b.Revision); ok {
+			b.StandardTimings = append(b.StandardTimings, m)
+		}
+	}
+
+	for i := 54; i < 126; i += 18 {
+		b.decodeDescriptor(block[i : i+18])
+	}
+}
+
+func decodeStandardTiming(b0, b1 byte, version, revision int) (m Mode, ok bool) {
+	if b0 == 0x01 && b1 == 0x01 || b0 == 0x00 {
+		return
+	}
+
+	m.Width = (int(b0) + 31) * 8
+	m.Refresh = int(b1&0x3f) + 60
+
+	switch b1 >> 6 {
+	case 0:
+		// 16:10 from EDID 1.3, 1:1 before
+		if version == 1 && revision < 3 {
+			m.Height = m.Width
+		} else {
+			m.Height = m.Width * 10 / 16
+		}
+	case 1:
+		m.Height = m.Width * 3 / 4
+	case 2:
+		m.Height = m.Width * 4 / 5
+	case 3:
+		m.Height = m.Width * 9 / 16
+	}
+
+	return m, true
+}
+
+func (b *Base) decodeDescriptor(d []byte) {
+	if d[0] != 0 || d[1] != 0 {
+		b.DetailedTimings = append(b.DetailedTimings, decodeDetailedTiming(d))
+		return
+	}
+
+	switch d[3] {
+	case descName:
+		b.Name = descriptorString(d[5:])
+
+	case descSerial:
+		b.SerialString = descriptorString(d[5:])
+
+	case descText:
+		b.Text = append(b.Text, descriptorString(d[5:]))
+
+	case descRangeLimits:
+		var (
+			flags  = d[4]
+			limits = RangeLimits{
+				MinVRate:      int(d[5]),
+				MaxVRate:      int(d[6]),
+				MinHRate:      int(d[7]),
+				MaxHRate:      int(d[8]),
+				MaxPixelClock: int(d[9]) * 10,
+			}
+		)
+
+		// EDID 1.4 rate offsets
+		if flags&0x01 != 0 {
+			limits.MinVRate += 255
+		}
+		if flags&0x02 != 0 {
+			limits.MaxVRate += 255
+		}
+		if flags&0x04 != 0 {
+			limits.MinHRate += 255
+		}
+		if flags&0x08 != 0 {
+			limits.MaxHRate += 255
+		}
+
+		b.RangeLimits = &limits
+	}
+}
+
+func descriptorString(b []byte) string {
+	var s = string(b)
+
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+
+	return strings.TrimRight(s, " \x00")
+}
+
+func decodeDetailedTiming(d []byte) (t DetailedTiming) {
+	t = DetailedTiming{
+		PixelClock:  int(binary.LittleEndian.Uint16(d[0:2])) * 10,
+		HActive:     int(d[2]) | int(d[4]>>4)<<8,
+		HBlank:      int(d[3]) | int(d[4]&0x0f)<<8,
+		VActive:     int(d[5]) | int(d[7]>>4)<<8,
+		VBlank:      int(d[6]) | int(d[7]&0x0f)<<8,
+		HSyncOffset: int(d[8]) | int(d[11]>>6)<<8,
+		HSyncWidth:  int(d[9]) | int(d[11]>>4&0x03)<<8,
+		VSyncOffset: int(d[10]>>4) | int(d[11]>>2&0x03)<<4,
+		VSyncWidth:  int(d[10]&0x0f) | int(d[11]&0x03)<<4,
+		WidthMm:     int(d[12]) | int(d[14]>>4)<<8,
+		HeightMm:    int(d[13]) | int(d[14]&0x0f)<<8,
+		Interlaced:  d[17]&0x80 != 0,
+	}
+
+	// Digital separate sync
+	if d[17]&0x18 == 0x18 {
+		t.VSyncPositive = d[17]&0x04 != 0
+		t.HSyncPositive = d[17]&0x02 != 0
+	}
+
+	return
+}
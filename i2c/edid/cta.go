@@ -0,0 +1,318 @@
+package edid
+
+import (
+	"fmt"
+)
+
+const TagCta = 0x02
+
+// CTA-861 data block tags
+const (
+	ctaAudio    = 1
+	ctaVideo    = 2
+	ctaVendor   = 3
+	ctaSpeaker  = 4
+	ctaExtended = 7
+)
+
+// IEEE OUIs of vendor specific data blocks
+const (
+	OuiHdmi      = 0x000c03
+	OuiHdmiForum = 0xc45dd8
+)
+
+// CtaExtension
+type CtaExtension struct {
+	Revision   int
+	Underscan  bool
+	BasicAudio bool
+	YCbCr444   bool
+	YCbCr422   bool
+	NativeDtds int
+
+	Audio           []ShortAudioDescriptor
+	Video           []ShortVideoDescriptor
+	SpeakerAlloc    byte
+	Hdmi            *HdmiVsdb
+	HdmiForum       *HdmiForumVsdb
+	VendorBlocks    []VendorBlock
+	ExtendedBlocks  []DataBlock
+	DetailedTimings []DetailedTiming
+}
+
+func (ext *CtaExtension) ExtensionTag() byte { return TagCta }
+
+// DataBlock
+type DataBlock struct {
+	Tag  int
+	Data []byte
+}
+
+// VendorBlock
+type VendorBlock struct {
+	Oui  uint32
+	Data []byte
+}
+
+// ShortAudioDescriptor
+type ShortAudioDescriptor struct {
+	Format      int // 1 = LPCM, 2 = AC-3, ...
+	MaxChannels int
+	SampleRates []int // Hz
+
+	// LPCM only
+	SampleSizes []int // bits
+
+	// Compressed formats: max bitrate (kbit/s) or format dependent value
+	Extra int
+}
+
+// ShortVideoDescriptor
+type ShortVideoDescriptor struct {
+	Vic    int
+	Native bool
+}
+
+// Mode of a known VIC, ok is false for unknown codes
+func (svd ShortVideoDescriptor) Mode() (m Mode, ok bool) {
+	m, ok = vicModes[svd.Vic]
+	return
+}
+
+// HdmiVsdb
+type HdmiVsdb struct {
+	PhysicalAddress uint16 // as in 1.2.0.0 => 0x1200
+	SupportsAI      bool
+	DeepColor48     bool
+	DeepColor36     bool
+	DeepColor30     bool
+	DeepColorY444   bool
+	DVIDual         bool
+	MaxTmdsClock    int // MHz, 0 if not given
+}
+
+func (v *HdmiVsdb) PhysicalAddressString() string {
+	var a = v.PhysicalAddress
+	return fmt.Sprintf("%d.%d.%d.%d", a>>12, a>>8&0xf, a>>4&0xf, a&0xf)
+}
+
+// HdmiForumVsdb
+type HdmiForumVsdb struct {
+	Version         int
+	MaxTmdsCharRate int // MHz, 0 if 340 MHz or below
+	Scdc            bool
+	ReadRequest     bool
+	Lte340Scramble  bool
+}
+
+var audioRates = [...]int{32000, 44100, 48000, 88200, 96000, 176400, 192000}
+
+func (ext *CtaExtension) decode(block []byte) error {
+	ext.Revision = int(block[1])
+
+	var dtdOffset = int(block[2])
+	if dtdOffset != 0 && (dtdOffset < 4 || dtdOffset > 127) {
+		return fmt.Errorf("edid: bad CTA DTD offset %d", dtdOffset)
+	}
+
+	if ext.Revision >= 2 {
+		ext.Underscan = block[3]&0x80 != 0
+		ext.BasicAudio = block[3]&0x40 != 0
+		ext.YCbCr444 = block[3]&0x20 != 0
+		ext.YCbCr422 = block[3]&0x10 != 0
+		ext.NativeDtds = int(block[3] & 0x0f)
+	}
+
+	var end = dtdOffset
+	if end == 0 {
+		end = 4
+	}
+
+	// Data block collection only exists in revision 3 onwards
+	for i := 4; ext.Revision >= 3 && i < end; {
+		var (
+			tag    = int(block[i] >> 5)
+			length = int(block[i] & 0x1f)
+		)
+
+		if i+1+length > end {
+			return fmt.Errorf("edid: CTA data block at %d overruns DTDs", i)
+		}
+
+		ext.decodeDataBlock(tag, block[i+1:i+1+length])
+		i += 1 + length
+	}
+
+	if dtdOffset != 0 {
+		for i := dtdOffset; i+18 <= 127; i += 18 {
+			var d = block[i : i+18]
+			if d[0] == 0 && d[1] == 0 {
+				break
+			}
+			ext.DetailedTimings = append(ext.DetailedTimings, decodeDetailedTiming(d))
+		}
+	}
+
+	return nil
+}
+
+func (ext *CtaExtension) decodeDataBlock(tag int, data []byte) {
+	switch tag {
+	case ctaAudio:
+		for i := 0; i+3 <= len(data); i += 3 {
+			ext.Audio = append(ext.Audio, decodeSad(data[i:i+3]))
+		}
+
+	case ctaVideo:
+		for _, v := range data {
+			var svd = ShortVideoDescriptor{Vic: int(v)}
+
+			// Bit 7 flags native only for VICs 1-64
+			if v&0x7f >= 1 && v&0x7f <= 64 && v&0x80 != 0 {
+				svd = ShortVideoDescriptor{Vic: int(v & 0x7f), Native: true}
+			}
+
+			ext.Video = append(ext.Video, svd)
+		}
+
+	case ctaVendor:
+		if len(data) < 3 {
+			return
+		}
+
+		var oui = uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+
+		switch oui {
+		case OuiHdmi:
+			ext.Hdmi = decodeHdmiVsdb(data)
+
+		case OuiHdmiForum:
+			ext.HdmiForum = decodeHdmiForumVsdb(data)
+
+		default:
+			ext.VendorBlocks = append(ext.VendorBlocks, VendorBlock{Oui: oui, Data: data[3:]})
+		}
+
+	case ctaSpeaker:
+		if len(data) > 0 {
+			ext.SpeakerAlloc = data[0]
+		}
+
+	case ctaExtended:
+		if len(data) > 0 {
+			ext.ExtendedBlocks = append(ext.ExtendedBlocks, DataBlock{Tag: int(data[0]), Data: data[1:]})
+		}
+	}
+}
+
+func decodeSad(b []byte) (sad ShortAudioDescriptor) {
+	sad = ShortAudioDescriptor{
+		Format:      int(b[0] >> 3 & 0x0f),
+		MaxChannels: int(b[0]&0x07) + 1,
+	}
+
+	for i, rate := range audioRates {
+		if b[1]&(1<<i) != 0 {
+			sad.SampleRates = append(sad.SampleRates, rate)
+		}
+	}
+
+	switch sad.Format {
+	case 1:
+		for i, bits := range [...]int{16, 20, 24} {
+			if b[2]&(1<<i) != 0 {
+				sad.SampleSizes = append(sad.SampleSizes, bits)
+			}
+		}
+
+	case 2, 3, 4, 5, 6, 7, 8:
+		sad.Extra = int(b[2]) * 8
+
+	default:
+		sad.Extra = int(b[2])
+	}
+
+	return
+}
+
+func decodeHdmiVsdb(data []byte) *HdmiVsdb {
+	var v HdmiVsdb
+
+	if len(data) >= 5 {
+		v.PhysicalAddress = uint16(data[3])<<8 | uint16(data[4])
+	}
+
+	if len(data) >= 6 {
+		v.SupportsAI = data[5]&0x80 != 0
+		v.DeepColor48 = data[5]&0x40 != 0
+		v.DeepColor36 = data[5]&0x20 != 0
+		v.DeepColor30 = data[5]&0x10 != 0
+		v.DeepColorY444 = data[5]&0x08 != 0
+		v.DVIDual = data[5]&0x01 != 0
+	}
+
+	if len(data) >= 7 {
+		v.MaxTmdsClock = int(data[6]) * 5
+	}
+
+	return &v
+}
+
+func decodeHdmiForumVsdb(data []byte) *HdmiForumVsdb {
+	var v HdmiForumVsdb
+
+	if len(data) >= 4 {
+		v.Version = int(data[3])
+	}
+
+	if len(data) >= 5 {
+		v.MaxTmdsCharRate = int(data[4]) * 5
+	}
+
+	if len(data) >= 6 {
+		v.Scdc = data[5]&0x80 != 0
+		v.ReadRequest = data[5]&0x40 != 0
+		v.Lte340Scramble = data[5]&0x08 != 0
+	}
+
+	return &v
+}
+
+// All modes advertised by the display, across the base block and the CTA
+// extension, without duplicates
+func (e *EDID) Modes() (modes []Mode) {
+	var seen = make(map[Mode]bool)
+
+	add := func(m Mode) {
+		if !seen[m] {
+			seen[m] = true
+			modes = append(modes, m)
+		}
+	}
+
+	for _, t := range e.DetailedTimings {
+		add(t.Mode())
+	}
+
+	for _, m := range e.EstablishedTimings {
+		add(m)
+	}
+
+	for _, m := range e.StandardTimings {
+		add(m)
+	}
+
+	if cta := e.Cta(); cta != nil {
+		for _, t := range cta.DetailedTimings {
+			add(t.Mode())
+		}
+
+		for _, svd := range cta.Video {
+			if m, ok := svd.Mode(); ok {
+				add(m)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,208 @@
+package edid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// See VESA E-EDID 1.4, E-DDC and CTA-861
+
+const (
+	Addr        = 0x50
+	SegmentAddr = 0x30
+
+	BlockLen = 128
+
+	// Blocks addressable by a single segment pointer value
+	blocksPerSegment = 2
+
+	DrmConnectorGlob = "/sys/class/drm/card*-*"
+)
+
+var (
+	ErrHeader   = errors.New("edid: bad header")
+	ErrChecksum = errors.New("edid: bad block checksum")
+	ErrLength   = errors.New("edid: truncated data")
+
+	header = [8]byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+)
+
+func checkBlock(b []byte) error {
+	var sum byte
+	for _, v := range b[:BlockLen] {
+		sum += v
+	}
+
+	if sum != 0 {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+// Read a single block, selecting the E-DDC segment when needed
+func readBlock(dev i2c.Bus, n int) ([]byte, error) {
+	var (
+		buf     = make([]byte, BlockLen)
+		segment = [1]byte{byte(n / blocksPerSegment)}
+		offset  = [1]byte{byte((n % blocksPerSegment) * BlockLen)}
+		raw     = [3]i2c.Msg{
+			{Addr: SegmentAddr, Flags: 0, Buf: segment[:]},
+			{Addr: Addr, Flags: 0, Buf: offset[:]},
+			{Addr: Addr, Flags: i2c.MsgRead, Buf: buf},
+		}
+		msgs = raw[:]
+	)
+
+	// Segment 0 is implied, and displays without E-DDC support NAK the pointer
+	if segment[0] == 0 {
+		msgs = raw[1:]
+	}
+
+	if err := dev.Rdwr(msgs); err != nil {
+		return nil, fmt.Errorf("edid: block %d: %w", n, err)
+	}
+
+	return buf, nil
+}
+
+// Read the base block and all extension blocks from a display's DDC adapter
+func ReadDevice(dev i2c.Bus) ([]byte, error) {
+	base, err := readBlock(dev, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkBlock(base); err != nil {
+		return nil, err
+	}
+
+	var data = base
+	for i := 1; i <= int(base[126]); i++ {
+		block, err := readBlock(dev, i)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, block...)
+	}
+
+	return data, nil
+}
+
+// Read the EDID exposed by a DRM connector, such as `card0-HDMI-A-1`
+func ReadSysfs(connector string) ([]byte, error) {
+	if !filepath.IsAbs(connector) {
+		connector = filepath.Join("/sys/class/drm", connector)
+	}
+
+	data, err := os.ReadFile(filepath.Join(connector, "edid"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("edid: connector `%s` has no EDID", filepath.Base(connector))
+	}
+
+	return data, nil
+}
+
+// Find DRM connectors that currently expose an EDID
+func FindConnectors() []string {
+	var (
+		names []string
+		found []string
+		err   error
+	)
+
+	if names, err = filepath.Glob(DrmConnectorGlob); err != nil {
+		return nil
+	}
+
+	for _, path := range names {
+		if fi, err := os.Stat(filepath.Join(path, "edid")); err == nil && fi.Size() > 0 {
+			found = append(found, filepath.Base(path))
+		}
+	}
+
+	return found
+}
+
+// EDID
+type EDID struct {
+	Base
+	Extensions []Extension
+	Raw        []byte
+}
+
+// Extension
+type Extension interface {
+	ExtensionTag() byte
+}
+
+// UnknownExtension
+type UnknownExtension struct {
+	Tag  byte
+	Data []byte
+}
+
+func (ext *UnknownExtension) ExtensionTag() byte { return ext.Tag }
+
+// Validate and decode EDID data, from either the DDC bus or sysfs
+func Parse(data []byte) (*EDID, error) {
+	if len(data) < BlockLen || len(data)%BlockLen != 0 {
+		return nil, ErrLength
+	}
+
+	if !bytes.Equal(data[:len(header)], header[:]) {
+		return nil, ErrHeader
+	}
+
+	var e = EDID{Raw: data}
+
+	for i := 0; i < len(data)/BlockLen; i++ {
+		if err := checkBlock(data[i*BlockLen:]); err != nil {
+			return nil, fmt.Errorf("%w (block %d)", err, i)
+		}
+	}
+
+	e.Base.decode(data[:BlockLen])
+
+	if n := int(data[126]); len(data) < (n+1)*BlockLen {
+		return nil, fmt.Errorf("%w: %d extension blocks declared, %d present", ErrLength, n, len(data)/BlockLen-1)
+	}
+
+	for i := 1; i < len(data)/BlockLen; i++ {
+		var block = data[i*BlockLen : (i+1)*BlockLen]
+
+		switch block[0] {
+		case TagCta:
+			var cta CtaExtension
+			if err := cta.decode(block); err != nil {
+				return nil, fmt.Errorf("block %d: %w", i, err)
+			}
+			e.Extensions = append(e.Extensions, &cta)
+
+		default:
+			e.Extensions = append(e.Extensions, &UnknownExtension{Tag: block[0], Data: block})
+		}
+	}
+
+	return &e, nil
+}
+
+func (e *EDID) Cta() *CtaExtension {
+	for _, ext := range e.Extensions {
+		if cta, ok := ext.(*CtaExtension); ok {
+			return cta
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package i2ctrace
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// Event
+type Event struct {
+	Task string
+	Pid  int
+	Cpu  int
+	Time time.Duration // since boot, per the trace clock
+	Name string
+
+	Adapter int
+
+	// i2c_write, i2c_read and i2c_reply
+	MsgNr int
+	Msg   i2c.Msg
+
+	// i2c_result
+	Count int
+	Ret   int
+}
+
+// The default trace_pipe line layout, allowing for the optional tgid and
+// irq-info columns
+var lineRe = regexp.MustCompile(`^\s*(.*)-(\d+)\s+(?:\(\s*[\d-]+\)\s+)?\[(\d+)\]\s+(?:\S+\s+)?(\d+)\.(\d+): (\w+): (.*)$`)
+
+// Parse one line of trace_pipe output
+func ParseEvent(line string) (*Event, error) {
+	var m = lineRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("i2ctrace: unrecognized line `%s`", line)
+	}
+
+	var ev = Event{
+		Task: m[1],
+		Name: m[6],
+	}
+
+	ev.Pid, _ = strconv.Atoi(m[2])
+	ev.Cpu, _ = strconv.Atoi(m[3])
+
+	secs, _ := strconv.ParseInt(m[4], 10, 64)
+	frac, _ := strconv.ParseInt(m[5], 10, 64)
+	for i := len(m[5]); i < 9; i++ {
+		frac *= 10
+	}
+	ev.Time = time.Duration(secs)*time.Second + time.Duration(frac)
+
+	if err := ev.parsePayload(m[7]); err != nil {
+		return nil, fmt.Errorf("i2ctrace: %s: %w", ev.Name, err)
+	}
+
+	return &ev, nil
+}
+
+// See include/trace/events/i2c.h
+func (ev *Event) parsePayload(s string) error {
+	switch ev.Name {
+	case "i2c_write", "i2c_read", "i2c_reply":
+		var (
+			addr   uint16
+			flags  int
+			length int
+		)
+
+		if _, err := fmt.Sscanf(s, "i2c-%d #%d a=%x f=%x l=%d", &ev.Adapter, &ev.MsgNr, &addr, &flags, &length); err != nil {
+			return err
+		}
+
+		ev.Msg = i2c.Msg{Addr: addr, Flags: flags, Buf: make([]byte, length)}
+
+		if ev.Name != "i2c_read" {
+			return parseData(s, ev.Msg.Buf)
+		}
+
+	case "i2c_result":
+		if _, err := fmt.Sscanf(s, "i2c-%d n=%d ret=%d", &ev.Adapter, &ev.Count, &ev.Ret); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown event")
+	}
+
+	return nil
+}
+
+// Decode the dash separated hex data, which the kernel truncates to 64
+// bytes
+func parseData(s string, buf []byte) error {
+	var start, end = strings.IndexByte(s, '['), strings.LastIndexByte(s, ']')
+	if start < 0 || end < start {
+		return fmt.Errorf("missing data")
+	}
+
+	var data = s[start+1 : end]
+	if data == "" {
+		return nil
+	}
+
+	for i, field := range strings.Split(data, "-") {
+		if i >= len(buf) {
+			break
+		}
+
+		b, err := hex.DecodeString(field)
+		if err != nil || len(b) != 1 {
+			return fmt.Errorf("bad data byte `%s`", field)
+		}
+
+		buf[i] = b[0]
+	}
+
+	return nil
+}
+
+// Transaction
+type Transaction struct {
+	Adapter int
+	Time    time.Duration
+	Task    string
+	Pid     int
+	Msgs    []i2c.Msg
+
+	// Number of messages transferred, or a negative errno
+	Ret int
+}
+
+func (txn *Transaction) Err() error {
+	if txn.Ret < 0 {
+		return unix.Errno(-txn.Ret)
+	}
+	return nil
+}
+
+// Such as `1234.567890 i2c-1 i2cget-4123 50 w [00] r [12] ret=2`
+func (txn *Transaction) String() string {
+	var (
+		b    strings.Builder
+		secs = txn.Time / time.Second
+		usec = (txn.Time % time.Second) / time.Microsecond
+		addr = -1
+	)
+
+	fmt.Fprintf(&b, "%d.%06d i2c-%d %s-%d", secs, usec, txn.Adapter, txn.Task, txn.Pid)
+
+	for _, msg := range txn.Msgs {
+		if int(msg.Addr) != addr {
+			addr = int(msg.Addr)
+			fmt.Fprintf(&b, " %02x", addr)
+		}
+
+		var dir = "w"
+		if msg.Flags&i2c.MsgRead != 0 {
+			dir = "r"
+		}
+
+		fmt.Fprintf(&b, " %s [% x]", dir, msg.Buf)
+	}
+
+	if err := txn.Err(); err != nil {
+		fmt.Fprintf(&b, " err=%s", err)
+	} else {
+		fmt.Fprintf(&b, " ret=%d", txn.Ret)
+	}
+
+	return b.String()
+}
+
+// Assembler groups events into transactions. Transfers on one adapter are
+// serialized by the adapter's bus lock, so at most one is pending per
+// adapter.
+type Assembler struct {
+	pending map[int]*Transaction
+}
+
+// Add an event, returning the transaction it completes, if any
+func (a *Assembler) Add(ev *Event) *Transaction {
+	if a.pending == nil {
+		a.pending = make(map[int]*Transaction)
+	}
+
+	var txn = a.pending[ev.Adapter]
+
+	switch ev.Name {
+	case "i2c_write", "i2c_read":
+		if txn == nil || ev.MsgNr == 0 {
+			txn = &Transaction{
+				Adapter: ev.Adapter,
+				Time:    ev.Time,
+				Task:    ev.Task,
+				Pid:     ev.Pid,
+			}
+			a.pending[ev.Adapter] = txn
+		}
+
+		txn.Msgs = append(txn.Msgs, ev.Msg)
+
+	case "i2c_reply":
+		if txn != nil && ev.MsgNr < len(txn.Msgs) {
+			txn.Msgs[ev.MsgNr].Buf = ev.Msg.Buf
+		}
+
+	case "i2c_result":
+		if txn == nil {
+			// Capture started mid transfer
+			return nil
+		}
+
+		delete(a.pending, ev.Adapter)
+		txn.Ret = ev.Ret
+		return txn
+	}
+
+	return nil
+}
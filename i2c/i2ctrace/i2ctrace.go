@@ -0,0 +1,186 @@
+package i2ctrace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Capture all I2C traffic on an adapter, including that of kernel drivers
+// and other processes, using the `i2c` tracepoints. Requires tracefs and
+// sufficient privileges.
+
+var (
+	TracefsPaths = []string{
+		"/sys/kernel/tracing",
+		"/sys/kernel/debug/tracing",
+	}
+
+	// Tracepoints emitted by `__i2c_transfer`, in the order they occur
+	Events = []string{
+		"i2c_write",
+		"i2c_read",
+		"i2c_reply",
+		"i2c_result",
+	}
+
+	ErrNoTracefs = errors.New("i2ctrace: tracefs not found")
+)
+
+const DefaultInstance = "i2ctrace"
+
+// Tracer
+type Tracer struct {
+	dir  string
+	pipe *os.File
+
+	Adapters []int
+}
+
+func findTracefs() (string, error) {
+	for _, path := range TracefsPaths {
+		if fi, err := os.Stat(filepath.Join(path, "instances")); err == nil && fi.IsDir() {
+			return path, nil
+		}
+	}
+
+	return "", ErrNoTracefs
+}
+
+// Create a tracefs instance named `name`, so as not to disturb the global
+// trace buffer, and enable the I2C events for the given adapters, or for all
+// adapters if none are given
+func Open(name string, adapters ...int) (t *Tracer, err error) {
+	if name == "" {
+		name = DefaultInstance
+	}
+
+	root, err := findTracefs()
+	if err != nil {
+		return nil, err
+	}
+
+	t = &Tracer{
+		dir:      filepath.Join(root, "instances", name),
+		Adapters: adapters,
+	}
+
+	if err = os.Mkdir(t.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			t.Close()
+			t = nil
+		}
+	}()
+
+	var filter = adapterFilter(adapters)
+
+	for _, ev := range Events {
+		var dir = filepath.Join(t.dir, "events", "i2c", ev)
+
+		if err = writeFile(filepath.Join(dir, "filter"), filter); err != nil {
+			return
+		}
+
+		if err = writeFile(filepath.Join(dir, "enable"), "1"); err != nil {
+			return
+		}
+	}
+
+	t.pipe, err = os.Open(filepath.Join(t.dir, "trace_pipe"))
+	return
+}
+
+func adapterFilter(adapters []int) string {
+	if len(adapters) == 0 {
+		// Clears any filter
+		return "0"
+	}
+
+	var terms []string
+	for _, nr := range adapters {
+		terms = append(terms, fmt.Sprintf("adapter_nr == %d", nr))
+	}
+
+	return strings.Join(terms, " || ")
+}
+
+func writeFile(path, value string) error {
+	if err := os.WriteFile(path, []byte(value+"\n"), 0); err != nil {
+		return fmt.Errorf("i2ctrace: %w", err)
+	}
+	return nil
+}
+
+// Disable the events and remove the instance, which also unblocks any Run
+// or Record in progress
+func (t *Tracer) Close() error {
+	if t.pipe != nil {
+		t.pipe.Close()
+	}
+
+	for _, ev := range Events {
+		writeFile(filepath.Join(t.dir, "events", "i2c", ev, "enable"), "0")
+	}
+
+	return os.Remove(t.dir)
+}
+
+// Read events from the trace pipe, sending each completed transaction to
+// `ch` until the tracer is closed
+func (t *Tracer) Run(ch chan<- *Transaction) error {
+	var (
+		scanner = bufio.NewScanner(t.pipe)
+		asm     Assembler
+	)
+
+	for scanner.Scan() {
+		ev, err := ParseEvent(scanner.Text())
+		if err != nil {
+			// Lost event notices and the like
+			continue
+		}
+
+		if txn := asm.Add(ev); txn != nil {
+			ch <- txn
+		}
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Write each completed transaction to `w`, one per line, until the tracer
+// is closed
+func (t *Tracer) Record(w io.Writer) error {
+	var (
+		ch   = make(chan *Transaction)
+		done = make(chan error, 1)
+	)
+
+	go func() {
+		done <- t.Run(ch)
+		close(ch)
+	}()
+
+	for txn := range ch {
+		if _, err := fmt.Fprintln(w, txn); err != nil {
+			t.Close()
+			for range ch {
+			}
+			return err
+		}
+	}
+
+	return <-done
+}
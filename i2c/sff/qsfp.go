@@ -0,0 +1,214 @@
+package sff
+
+import (
+	"fmt"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+const (
+	qsfpPageSelect = 127
+	qsfpUpperPage  = 128
+	qsfpChannels   = 4
+
+	// Byte 2, status
+	qsfpFlatMem = 0x04
+)
+
+var qsfpCompliance = []bitName{
+	{131, 0x40, "10GBASE-LRM"},
+	{131, 0x20, "10GBASE-LR"},
+	{131, 0x10, "10GBASE-SR"},
+	{131, 0x08, "40GBASE-CR4"},
+	{131, 0x04, "40GBASE-SR4"},
+	{131, 0x02, "40GBASE-LR4"},
+	{131, 0x01, "40G active cable"},
+	{132, 0x08, "40G OTN"},
+	{134, 0x08, "1000BASE-T"},
+	{134, 0x04, "1000BASE-CX"},
+	{134, 0x02, "1000BASE-LX"},
+	{134, 0x01, "1000BASE-SX"},
+}
+
+// Extended specification compliance codes, SFF-8024 table 4-4
+var qsfpExtendedCompliance = map[byte]string{
+	0x01: "100G AOC or 25GAUI C2M AOC",
+	0x02: "100GBASE-SR4 or 25GBASE-SR",
+	0x03: "100GBASE-LR4 or 25GBASE-LR",
+	0x04: "100GBASE-ER4 or 25GBASE-ER",
+	0x05: "100GBASE-SR10",
+	0x06: "100G CWDM4",
+	0x07: "100G PSM4",
+	0x08: "100G ACC or 25GAUI C2M ACC",
+	0x0b: "100GBASE-CR4 or 25GBASE-CR CA-L",
+	0x0c: "25GBASE-CR CA-S",
+	0x0d: "25GBASE-CR CA-N",
+	0x10: "40GBASE-ER4",
+	0x11: "4 x 10GBASE-SR",
+	0x12: "40G PSM4",
+	0x17: "100G CLR4",
+	0x18: "100G AOC or 25GAUI C2M AOC, low BER",
+	0x1a: "100GE-DWDM2",
+}
+
+// Qsfp
+type Qsfp struct {
+	dev i2c.Bus
+}
+
+func NewQsfp(dev i2c.Bus) *Qsfp { return &Qsfp{dev: dev} }
+
+func (m *Qsfp) ReadLower() ([]byte, error) {
+	return readEeprom(m.dev, AddrA0, 0, qsfpUpperPage)
+}
+
+// Read the upper half of memory with the given page selected, restoring
+// page 0 afterwards
+func (m *Qsfp) ReadPage(page byte) (data []byte, err error) {
+	if err = m.selectPage(page); err != nil {
+		return
+	}
+
+	if page != 0 {
+		defer func() {
+			if rerr := m.selectPage(0); err == nil {
+				err = rerr
+			}
+		}()
+	}
+
+	return readEeprom(m.dev, AddrA0, qsfpUpperPage, PageLen-qsfpUpperPage)
+}
+
+func (m *Qsfp) selectPage(page byte) error {
+	var (
+		buf  = [2]byte{qsfpPageSelect, page}
+		msgs = [1]i2c.Msg{{Addr: AddrA0, Flags: 0, Buf: buf[:]}}
+	)
+
+	return m.dev.Rdwr(msgs[:])
+}
+
+// Read a full 256 byte image of the lower page and the given upper page
+func (m *Qsfp) readImage(page byte) ([]byte, error) {
+	lower, err := m.ReadLower()
+	if err != nil {
+		return nil, err
+	}
+
+	upper, err := m.ReadPage(page)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(lower, upper...), nil
+}
+
+func (m *Qsfp) Identity() (*Identity, error) {
+	image, err := m.readImage(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeQsfpIdentity(image)
+}
+
+func (m *Qsfp) Diagnostics() (*Diagnostics, *Thresholds, error) {
+	lower, err := m.ReadLower()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Thresholds live in page 3, which flat memory modules do not have
+	var page3 []byte
+	if lower[2]&qsfpFlatMem == 0 {
+		if page3, err = m.ReadPage(3); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return DecodeQsfpDiagnostics(lower, page3)
+}
+
+// Decode the lower page followed by upper page 0
+func DecodeQsfpIdentity(image []byte) (*Identity, error) {
+	if len(image) < PageLen {
+		return nil, fmt.Errorf("sff: short QSFP image, %d bytes", len(image))
+	}
+
+	var id = Identity{
+		Identifier:     image[128],
+		Connector:      image[130],
+		Encoding:       image[139],
+		NominalBitrate: int(image[140]) * 100,
+		VendorName:     fieldString(image[148:164]),
+		VendorOui:      uint32(image[165])<<16 | uint32(image[166])<<8 | uint32(image[167]),
+		VendorPN:       fieldString(image[168:184]),
+		VendorRev:      fieldString(image[184:186]),
+		VendorSN:       fieldString(image[196:212]),
+		DateCode:       fieldString(image[212:220]),
+		Compliance:     decodeBits(image, qsfpCompliance),
+	}
+
+	// Nominal rate above 25.4 GBd is given in byte 222, in units of 250 MBd
+	if image[140] == 0xff {
+		id.NominalBitrate = int(image[222]) * 250
+	}
+
+	// Extended compliance in byte 192, flagged by byte 131 bit 7
+	if image[131]&0x80 != 0 {
+		if s, ok := qsfpExtendedCompliance[image[192]]; ok {
+			id.Compliance = append(id.Compliance, s)
+		}
+	}
+
+	// Bytes 186-187 hold copper attenuation rather than wavelength for
+	// copper transmitters, see byte 147
+	if image[147]>>4 < 0x0a {
+		id.Wavelength = float64(be16(image[186:188])) / 20
+	}
+
+	return &id, nil
+}
+
+// Decode the lower page, with thresholds from upper page 3 when available
+func DecodeQsfpDiagnostics(lower, page3 []byte) (*Diagnostics, *Thresholds, error) {
+	if len(lower) < qsfpUpperPage {
+		return nil, nil, fmt.Errorf("sff: short QSFP lower page, %d bytes", len(lower))
+	}
+
+	var diag = Diagnostics{
+		Temperature: rawTemperature(int16(be16(lower[22:24]))),
+		Voltage:     rawVoltage(be16(lower[26:28])),
+	}
+
+	for i := 0; i < qsfpChannels; i++ {
+		diag.Channels = append(diag.Channels, ChannelDiagnostics{
+			RxPower: rawPower(be16(lower[34+2*i:])),
+			Bias:    rawBias(be16(lower[42+2*i:])),
+			TxPower: rawPower(be16(lower[50+2*i:])),
+		})
+	}
+
+	if len(page3) < qsfpUpperPage {
+		return &diag, nil, nil
+	}
+
+	// Page 3 offsets, relative to the start of upper memory
+	var (
+		temp  = func(b []byte) float64 { return rawTemperature(int16(be16(b))) }
+		volt  = func(b []byte) float64 { return rawVoltage(be16(b)) }
+		bias  = func(b []byte) float64 { return rawBias(be16(b)) }
+		power = func(b []byte) float64 { return rawPower(be16(b)) }
+
+		thresholds = Thresholds{
+			Temperature: decodeThreshold(page3[0:8], temp),
+			Voltage:     decodeThreshold(page3[16:24], volt),
+			RxPower:     decodeThreshold(page3[48:56], power),
+			Bias:        decodeThreshold(page3[56:64], bias),
+			TxPower:     decodeThreshold(page3[64:72], power),
+		}
+	)
+
+	return &diag, &thresholds, nil
+}
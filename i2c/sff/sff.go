@@ -0,0 +1,205 @@
+package sff
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// See SFF-8024, SFF-8472 (SFP) and SFF-8636 (QSFP)
+
+const (
+	AddrA0 = 0x50
+	AddrA2 = 0x51
+
+	PageLen = 256
+
+	// Largest read issued in a single transfer, as many adapters and
+	// modules cannot manage a whole page at once
+	readChunk = 32
+)
+
+// Identifier values, SFF-8024 table 4-1
+const (
+	IdentifierGbic     = 0x01
+	IdentifierSfp      = 0x03
+	IdentifierQsfp     = 0x0c
+	IdentifierQsfpPlus = 0x0d
+	IdentifierQsfp28   = 0x11
+)
+
+var identifierNames = map[byte]string{
+	0x00: "unknown",
+	0x01: "GBIC",
+	0x02: "soldered",
+	0x03: "SFP/SFP+/SFP28",
+	0x0c: "QSFP",
+	0x0d: "QSFP+",
+	0x11: "QSFP28",
+}
+
+var connectorNames = map[byte]string{
+	0x01: "SC",
+	0x07: "LC",
+	0x0b: "optical pigtail",
+	0x0c: "MPO 1x12",
+	0x0d: "MPO 2x16",
+	0x21: "copper pigtail",
+	0x22: "RJ45",
+	0x23: "no separable connector",
+}
+
+// Identity
+type Identity struct {
+	Identifier     byte
+	Connector      byte
+	Encoding       byte
+	VendorName     string
+	VendorOui      uint32
+	VendorPN       string
+	VendorRev      string
+	VendorSN       string
+	DateCode       string
+	Compliance     []string
+	NominalBitrate int     // MBd
+	Wavelength     float64 // nm, 0 for copper
+}
+
+func (id *Identity) IdentifierName() string { return lookupName(identifierNames, id.Identifier) }
+
+func (id *Identity) ConnectorName() string { return lookupName(connectorNames, id.Connector) }
+
+func lookupName(names map[byte]string, v byte) string {
+	if s, ok := names[v]; ok {
+		return s
+	}
+	return "unknown"
+}
+
+// Diagnostics
+type Diagnostics struct {
+	Temperature float64 // degrees C
+	Voltage     float64 // V
+	Channels    []ChannelDiagnostics
+}
+
+// ChannelDiagnostics
+type ChannelDiagnostics struct {
+	Bias    float64 // mA
+	TxPower float64 // mW
+	RxPower float64 // mW
+}
+
+// Threshold
+type Threshold struct {
+	HighAlarm   float64
+	LowAlarm    float64
+	HighWarning float64
+	LowWarning  float64
+}
+
+// Level
+type Level int
+
+const (
+	Normal Level = iota
+	Warning
+	Alarm
+)
+
+func (l Level) String() string {
+	switch l {
+	case Warning:
+		return "warning"
+	case Alarm:
+		return "alarm"
+	default:
+		return "normal"
+	}
+}
+
+func (t Threshold) Check(v float64) Level {
+	switch {
+	case v >= t.HighAlarm || v <= t.LowAlarm:
+		return Alarm
+	case v >= t.HighWarning || v <= t.LowWarning:
+		return Warning
+	default:
+		return Normal
+	}
+}
+
+// Thresholds
+type Thresholds struct {
+	Temperature Threshold
+	Voltage     Threshold
+	Bias        Threshold
+	TxPower     Threshold
+	RxPower     Threshold
+}
+
+func MilliwattsToDbm(mw float64) float64 {
+	if mw <= 0 {
+		return math.Inf(-1)
+	}
+	return 10 * math.Log10(mw)
+}
+
+// Raw unit conversions shared by SFF-8472 and SFF-8636
+func rawTemperature(v int16) float64 { return float64(v) / 256 }
+func rawVoltage(v uint16) float64    { return float64(v) / 10000 }
+func rawBias(v uint16) float64       { return float64(v) * 0.002 }
+func rawPower(v uint16) float64      { return float64(v) / 10000 }
+
+func be16(b []byte) uint16 { return binary.BigEndian.Uint16(b) }
+
+func fieldString(b []byte) string { return strings.TrimRight(string(b), " \x00") }
+
+// Read `n` bytes from `offset` of the EEPROM at `addr`
+func readEeprom(dev i2c.Bus, addr uint16, offset, n int) ([]byte, error) {
+	var buf = make([]byte, n)
+
+	for i := 0; i < n; i += readChunk {
+		var (
+			off  = [1]byte{byte(offset + i)}
+			msgs = [2]i2c.Msg{
+				{Addr: addr, Flags: 0, Buf: off[:]},
+				{Addr: addr, Flags: i2c.MsgRead, Buf: buf[i:min(i+readChunk, n)]},
+			}
+		)
+
+		if err := dev.Rdwr(msgs[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+type bitName struct {
+	offset int
+	mask   byte
+	name   string
+}
+
+func decodeBits(page []byte, table []bitName) (names []string) {
+	for _, b := range table {
+		if page[b.offset]&b.mask != 0 {
+			names = append(names, b.name)
+		}
+	}
+	return
+}
+
+// Threshold quads are stored as high alarm, low alarm, high warning and low
+// warning
+func decodeThreshold(b []byte, conv func([]byte) float64) Threshold {
+	return Threshold{
+		HighAlarm:   conv(b[0:2]),
+		LowAlarm:    conv(b[2:4]),
+		HighWarning: conv(b[4:6]),
+		LowWarning:  conv(b[6:8]),
+	}
+}
@@ -0,0 +1,220 @@
+package sff
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+var ErrNoDiagnostics = errors.New("sff: module does not implement digital diagnostics")
+
+// SFF-8472 byte 92, diagnostic monitoring type
+const (
+	diagImplemented = 0x40
+	diagExternalCal = 0x10
+)
+
+var sfpCompliance = []bitName{
+	{3, 0x80, "10GBASE-ER"},
+	{3, 0x40, "10GBASE-LRM"},
+	{3, 0x20, "10GBASE-LR"},
+	{3, 0x10, "10GBASE-SR"},
+	{3, 0x08, "InfiniBand 1X SX"},
+	{3, 0x04, "InfiniBand 1X LX"},
+	{3, 0x02, "InfiniBand 1X copper active"},
+	{3, 0x01, "InfiniBand 1X copper passive"},
+	{4, 0x80, "ESCON MMF"},
+	{4, 0x40, "ESCON SMF"},
+	{4, 0x20, "OC-192 short reach"},
+	{6, 0x80, "BASE-PX"},
+	{6, 0x40, "BASE-BX10"},
+	{6, 0x20, "100BASE-FX"},
+	{6, 0x10, "100BASE-LX/LX10"},
+	{6, 0x08, "1000BASE-T"},
+	{6, 0x04, "1000BASE-CX"},
+	{6, 0x02, "1000BASE-LX"},
+	{6, 0x01, "1000BASE-SX"},
+	{8, 0x08, "active cable"},
+	{8, 0x04, "passive cable"},
+}
+
+// Sfp
+type Sfp struct {
+	dev i2c.Bus
+}
+
+func NewSfp(dev i2c.Bus) *Sfp { return &Sfp{dev: dev} }
+
+func (m *Sfp) ReadA0() ([]byte, error) { return readEeprom(m.dev, AddrA0, 0, PageLen) }
+func (m *Sfp) ReadA2() ([]byte, error) { return readEeprom(m.dev, AddrA2, 0, PageLen) }
+
+func (m *Sfp) Identity() (*Identity, error) {
+	a0, err := m.ReadA0()
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeSfpIdentity(a0)
+}
+
+func (m *Sfp) Diagnostics() (*Diagnostics, *Thresholds, error) {
+	a0, err := m.ReadA0()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if a0[92]&diagImplemented == 0 {
+		return nil, nil, ErrNoDiagnostics
+	}
+
+	a2, err := m.ReadA2()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return DecodeSfpDiagnostics(a0, a2)
+}
+
+func DecodeSfpIdentity(a0 []byte) (*Identity, error) {
+	if len(a0) < 96 {
+		return nil, fmt.Errorf("sff: short A0 page, %d bytes", len(a0))
+	}
+
+	var id = Identity{
+		Identifier:     a0[0],
+		Connector:      a0[2],
+		Encoding:       a0[11],
+		NominalBitrate: int(a0[12]) * 100,
+		VendorName:     fieldString(a0[20:36]),
+		VendorOui:      uint32(a0[37])<<16 | uint32(a0[38])<<8 | uint32(a0[39]),
+		VendorPN:       fieldString(a0[40:56]),
+		VendorRev:      fieldString(a0[56:60]),
+		VendorSN:       fieldString(a0[68:84]),
+		DateCode:       fieldString(a0[84:92]),
+		Compliance:     decodeBits(a0, sfpCompliance),
+	}
+
+	// Nominal rate above 25.4 GBd is given in byte 66, in units of 250 MBd
+	if a0[12] == 0xff {
+		id.NominalBitrate = int(a0[66]) * 250
+	}
+
+	// Bytes 60-61 hold cable compliance rather than wavelength for copper
+	if a0[8]&0x0c == 0 {
+		id.Wavelength = float64(be16(a0[60:62]))
+	}
+
+	return &id, nil
+}
+
+// sfpCalibration
+type sfpCalibration struct {
+	external              bool
+	rxPower               [5]float64 // Rx_PWR(4) .. Rx_PWR(0)
+	biasSlope, biasOffset float64
+	txSlope, txOffset     float64
+	tempSlope, tempOffset float64
+	voltSlope, voltOffset float64
+}
+
+func newSfpCalibration(a0, a2 []byte) (cal sfpCalibration) {
+	if a0[92]&diagExternalCal == 0 {
+		return
+	}
+
+	cal.external = true
+
+	for i := range cal.rxPower {
+		var bits = uint32(a2[56+4*i])<<24 | uint32(a2[57+4*i])<<16 | uint32(a2[58+4*i])<<8 | uint32(a2[59+4*i])
+		cal.rxPower[i] = float64(math.Float32frombits(bits))
+	}
+
+	// Slopes are unsigned 8.8 fixed point, offsets signed in raw units
+	slope := func(b []byte) float64 { return float64(be16(b)) / 256 }
+	offset := func(b []byte) float64 { return float64(int16(be16(b))) }
+
+	cal.biasSlope, cal.biasOffset = slope(a2[76:78]), offset(a2[78:80])
+	cal.txSlope, cal.txOffset = slope(a2[80:82]), offset(a2[82:84])
+	cal.tempSlope, cal.tempOffset = slope(a2[84:86]), offset(a2[86:88])
+	cal.voltSlope, cal.voltOffset = slope(a2[88:90]), offset(a2[90:92])
+
+	return
+}
+
+func (cal *sfpCalibration) temperature(b []byte) float64 {
+	var raw = float64(int16(be16(b)))
+	if cal.external {
+		raw = cal.tempSlope*raw + cal.tempOffset
+	}
+	return raw / 256
+}
+
+func (cal *sfpCalibration) voltage(b []byte) float64 {
+	var raw = float64(be16(b))
+	if cal.external {
+		raw = cal.voltSlope*raw + cal.voltOffset
+	}
+	return raw / 10000
+}
+
+func (cal *sfpCalibration) bias(b []byte) float64 {
+	var raw = float64(be16(b))
+	if cal.external {
+		raw = cal.biasSlope*raw + cal.biasOffset
+	}
+	return raw * 0.002
+}
+
+func (cal *sfpCalibration) txPower(b []byte) float64 {
+	var raw = float64(be16(b))
+	if cal.external {
+		raw = cal.txSlope*raw + cal.txOffset
+	}
+	return raw / 10000
+}
+
+func (cal *sfpCalibration) rxPowerValue(b []byte) float64 {
+	var raw = float64(be16(b))
+	if cal.external {
+		var sum float64
+		for _, c := range cal.rxPower {
+			sum = sum*raw + c
+		}
+		raw = sum
+	}
+	return raw / 10000
+}
+
+func DecodeSfpDiagnostics(a0, a2 []byte) (*Diagnostics, *Thresholds, error) {
+	if len(a0) < 96 || len(a2) < 106 {
+		return nil, nil, fmt.Errorf("sff: short A0/A2 pages")
+	}
+
+	if a0[92]&diagImplemented == 0 {
+		return nil, nil, ErrNoDiagnostics
+	}
+
+	var (
+		cal  = newSfpCalibration(a0, a2)
+		diag = Diagnostics{
+			Temperature: cal.temperature(a2[96:98]),
+			Voltage:     cal.voltage(a2[98:100]),
+			Channels: []ChannelDiagnostics{{
+				Bias:    cal.bias(a2[100:102]),
+				TxPower: cal.txPower(a2[102:104]),
+				RxPower: cal.rxPowerValue(a2[104:106]),
+			}},
+		}
+		thresholds = Thresholds{
+			Temperature: decodeThreshold(a2[0:8], cal.temperature),
+			Voltage:     decodeThreshold(a2[8:16], cal.voltage),
+			Bias:        decodeThreshold(a2[16:24], cal.bias),
+			TxPower:     decodeThreshold(a2[24:32], cal.txPower),
+			RxPower:     decodeThreshold(a2[32:40], cal.rxPowerValue),
+		}
+	)
+
+	return &diag, &thresholds, nil
+}
@@ -0,0 +1,236 @@
+package smbalert
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"go.pdmccormick.com/linuxuapi/gpio"
+	"go.pdmccormick.com/linuxuapi/i2c"
+)
+
+// SMBus alerts: devices pull the shared SMBALERT# line low, and the host
+// finds out which by reading from the Alert Response Address. Each device
+// asserting an alert answers with its own address, the lowest address
+// winning arbitration, and releases the line once it has been answered.
+// See SMBus 3.0 appendix A.
+
+const (
+	AraAddr = 0x0c
+
+	DefaultPollInterval = 100 * time.Millisecond
+
+	// Upper bound on ARA reads for one alert, in case a device keeps
+	// asserting the line
+	DefaultMaxResponses = 16
+)
+
+var ErrClosed = errors.New("smbalert: manager closed")
+
+// Alert
+type Alert struct {
+	Addr uint16
+
+	// Low bit of the ARA response, whose meaning is device specific
+	Flag bool
+
+	Time time.Time
+}
+
+// Handler
+type Handler func(Alert)
+
+// Manager
+type Manager struct {
+	bus  i2c.Bus
+	line *gpio.Lines
+
+	mu       sync.Mutex
+	handlers map[uint16]Handler
+	done     chan struct{}
+	closed   bool
+
+	// With a line, how often to check its level in case an edge was
+	// missed; without one, how often to issue the ARA read
+	PollInterval time.Duration
+
+	MaxResponses int
+
+	// Alerts with no handler for their address are sent here, if set
+	Events chan Alert
+}
+
+// Create a manager that polls the ARA, for buses whose SMBALERT# line is not
+// connected to a GPIO
+func NewManager(bus i2c.Bus) *Manager {
+	return &Manager{
+		bus:          bus,
+		handlers:     make(map[uint16]Handler),
+		done:         make(chan struct{}),
+		PollInterval: DefaultPollInterval,
+		MaxResponses: DefaultMaxResponses,
+	}
+}
+
+// Wait for falling edges on SMBALERT# at `offset` of `chip`, rather than
+// polling
+func (m *Manager) UseLine(chip *gpio.Chip, offset int, flags gpio.LineFlags) error {
+	line, err := chip.RequestLine("smbalert", offset, gpio.LineConfig{
+		Flags: gpio.FlagInput | gpio.FlagEdgeFalling | flags,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.line = line
+	return nil
+}
+
+// Call `h` for alerts from `addr`, or stop doing so if `h` is nil
+func (m *Manager) Handle(addr uint16, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if h == nil {
+		delete(m.handlers, addr)
+	} else {
+		m.handlers[addr] = h
+	}
+}
+
+// Stop Run, and release the alert line
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+
+	m.closed = true
+	close(m.done)
+
+	if m.line != nil {
+		return m.line.Close()
+	}
+
+	return nil
+}
+
+func (m *Manager) asserted() (bool, error) {
+	if m.line == nil {
+		return true, nil
+	}
+
+	high, err := m.line.Value(0)
+	return !high, err
+}
+
+// Read the ARA until no device responds or the line is released, returning
+// the alerts found. Without a line, an empty result is normal.
+func (m *Manager) Poll() ([]Alert, error) {
+	var (
+		alerts []Alert
+		buf    [1]byte
+	)
+
+	for i := 0; i < m.MaxResponses; i++ {
+		if asserted, err := m.asserted(); err != nil {
+			return alerts, err
+		} else if !asserted {
+			break
+		}
+
+		var msgs = [1]i2c.Msg{{Addr: AraAddr, Flags: i2c.MsgRead, Buf: buf[:]}}
+
+		if err := m.bus.Rdwr(msgs[:]); err != nil {
+			if errors.Is(err, unix.ENXIO) || errors.Is(err, unix.EIO) {
+				break
+			}
+			return alerts, err
+		}
+
+		alerts = append(alerts, Alert{
+			Addr: uint16(buf[0] >> 1),
+			Flag: buf[0]&1 != 0,
+			Time: time.Now(),
+		})
+	}
+
+	return alerts, nil
+}
+
+func (m *Manager) dispatch(alerts []Alert) {
+	for _, alert := range alerts {
+		m.mu.Lock()
+		var h = m.handlers[alert.Addr]
+		m.mu.Unlock()
+
+		switch {
+		case h != nil:
+			h(alert)
+
+		case m.Events != nil:
+			select {
+			case m.Events <- alert:
+			case <-m.done:
+				return
+			}
+		}
+	}
+}
+
+// Handle alerts as they occur, until Close
+func (m *Manager) Run() error {
+	for {
+		if err := m.wait(); err != nil {
+			m.mu.Lock()
+			var closed = m.closed
+			m.mu.Unlock()
+
+			if closed {
+				return ErrClosed
+			}
+			return err
+		}
+
+		alerts, err := m.Poll()
+		if err != nil {
+			return err
+		}
+
+		if len(alerts) == 0 && m.line != nil {
+			// Asserted, but nobody answered, so back off rather than spin
+			select {
+			case <-time.After(m.PollInterval):
+			case <-m.done:
+				return ErrClosed
+			}
+		}
+
+		m.dispatch(alerts)
+	}
+}
+
+// Wait for the next reason to poll
+func (m *Manager) wait() error {
+	if m.line == nil {
+		select {
+		case <-time.After(m.PollInterval):
+			return nil
+		case <-m.done:
+			return ErrClosed
+		}
+	}
+
+	// The line is level triggered in effect, so check it before waiting for
+	// an edge that may already have passed
+	if asserted, err := m.asserted(); err != nil || asserted {
+		return err
+	}
+
+	_, err := m.line.WaitEvent(m.PollInterval)
+	return err
+}
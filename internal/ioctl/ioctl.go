@@ -0,0 +1,47 @@
+// Package ioctl issues ioctls on open files through SyscallConn, as `Fd`
+// would put the descriptor back into blocking mode and defeat read
+// deadlines.
+package ioctl
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Pointer issues an ioctl taking a pointer to its argument, returning the
+// ioctl's result
+func Pointer(f *os.File, req uintptr, arg unsafe.Pointer) (r uintptr, err error) {
+	return control(f, func(fd uintptr) (uintptr, uintptr, syscall.Errno) {
+		return unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg))
+	})
+}
+
+// Value issues an ioctl taking its argument by value, returning the ioctl's
+// result
+func Value(f *os.File, req, arg uintptr) (r uintptr, err error) {
+	return control(f, func(fd uintptr) (uintptr, uintptr, syscall.Errno) {
+		return unix.Syscall(unix.SYS_IOCTL, fd, req, arg)
+	})
+}
+
+func control(f *os.File, call func(fd uintptr) (uintptr, uintptr, syscall.Errno)) (r uintptr, err error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var errno syscall.Errno
+	if err := rc.Control(func(fd uintptr) {
+		r, _, errno = call(fd)
+	}); err != nil {
+		return 0, err
+	}
+
+	if errno != 0 {
+		return 0, errno
+	}
+	return r, nil
+}
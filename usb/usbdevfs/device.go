@@ -0,0 +1,132 @@
+package usbdevfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"go.pdmccormick.com/linuxuapi/internal/ioctl"
+	"golang.org/x/sys/unix"
+)
+
+// Host side access to USB devices, through their usbfs nodes in
+// `/dev/bus/usb`, for talking to gadgets from the same machine over
+// `dummy_hcd`, or from another host.
+//
+// See <https://docs.kernel.org/driver-api/usb/usb.html#the-usb-character-device-nodes>
+
+const DevBusUsbPath = "/dev/bus/usb"
+
+// Endpoint address direction bit, set for IN endpoints
+const EndpointIn = 0x80
+
+// Longest single bulk transfer usbfs accepts by default, as set by the
+// `usbfs_memory_mb` module parameter
+const MaxBulkLen = 16 << 20
+
+var ErrTimeout = errors.New("usbdevfs: transfer timed out")
+
+// Device
+type Device struct {
+	f *os.File
+}
+
+// Open a device node, such as `/dev/bus/usb/001/002`
+func OpenDevice(name string) (*Device, error) {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Device{f: f}, nil
+}
+
+func (dev *Device) Close() error { return dev.f.Close() }
+
+func (dev *Device) ioctl(req uintptr, arg unsafe.Pointer) (int, error) {
+	r, err := ioctl.Pointer(dev.f, req, arg)
+	return int(r), err
+}
+
+// Claim an interface for use by this process. Fails with EBUSY if a kernel
+// driver is bound to it, unless it is detached first.
+func (dev *Device) ClaimInterface(iface int) error {
+	var v = uint32(iface)
+	_, err := dev.ioctl(_USBDEVFS_CLAIMINTERFACE, unsafe.Pointer(&v))
+	return err
+}
+
+func (dev *Device) ReleaseInterface(iface int) error {
+	var v = uint32(iface)
+	_, err := dev.ioctl(_USBDEVFS_RELEASEINTERFACE, unsafe.Pointer(&v))
+	return err
+}
+
+// Unbind any kernel driver from an interface, such as usbtest from a
+// Gadget Zero function. Fails with ENODATA when none is bound.
+func (dev *Device) DetachKernelDriver(iface int) error {
+	var cmd = usbdevfs_ioctl{
+		ifno:      int32(iface),
+		ioctlCode: int32(_USBDEVFS_DISCONNECT),
+	}
+
+	_, err := dev.ioctl(_USBDEVFS_IOCTL, unsafe.Pointer(&cmd))
+	return err
+}
+
+// Select the active configuration, by its bConfigurationValue. Fails with
+// EBUSY while another driver has an interface claimed.
+func (dev *Device) SetConfiguration(config int) error {
+	var v = uint32(config)
+	_, err := dev.ioctl(_USBDEVFS_SETCONFIGURATION, unsafe.Pointer(&v))
+	return err
+}
+
+// Select an alternate setting of a claimed interface
+func (dev *Device) SetInterface(iface, alt int) error {
+	var v = usbdevfs_setinterface{iface: uint32(iface), altsetting: uint32(alt)}
+	_, err := dev.ioctl(_USBDEVFS_SETINTERFACE, unsafe.Pointer(&v))
+	return err
+}
+
+// Clear a halted endpoint, after an EPIPE
+func (dev *Device) ClearHalt(ep int) error {
+	var v = uint32(ep)
+	_, err := dev.ioctl(_USBDEVFS_CLEAR_HALT, unsafe.Pointer(&v))
+	return err
+}
+
+func (dev *Device) Reset() error {
+	_, err := dev.ioctl(_USBDEVFS_RESET, nil)
+	return err
+}
+
+// Perform a bulk transfer on an endpoint of a claimed interface, reading
+// into `p` for IN endpoints and writing from it for OUT. The transfer
+// blocks the calling thread until it completes or the timeout passes, with
+// no timeout when zero.
+func (dev *Device) Bulk(ep int, p []byte, timeout time.Duration) (int, error) {
+	if len(p) > MaxBulkLen {
+		return 0, fmt.Errorf("usbdevfs: bulk transfer of %d bytes longer than %d", len(p), MaxBulkLen)
+	}
+
+	var xfer = usbdevfs_bulktransfer{
+		ep:      uint32(ep),
+		len:     uint32(len(p)),
+		timeout: uint32(timeout.Milliseconds()),
+	}
+
+	if len(p) > 0 {
+		xfer.data = unsafe.Pointer(&p[0])
+	}
+
+	n, err := dev.ioctl(_USBDEVFS_BULK, unsafe.Pointer(&xfer))
+
+	if err == unix.ETIMEDOUT {
+		return 0, ErrTimeout
+	}
+
+	return n, err
+}
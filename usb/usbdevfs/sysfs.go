@@ -0,0 +1,154 @@
+package usbdevfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const SysBusUsbDevicesPath = "/sys/bus/usb/devices"
+
+// Endpoint transfer types, as sysfs names them
+const (
+	EndpointControl   = "Control"
+	EndpointIsoc      = "Isoc"
+	EndpointBulk      = "Bulk"
+	EndpointInterrupt = "Interrupt"
+)
+
+// DeviceInfo describes a device from sysfs, before it is opened
+type DeviceInfo struct {
+	// Device directory in sysfs, such as `/sys/bus/usb/devices/1-1`
+	SysPath string
+
+	Busnum    int
+	Devnum    int
+	IdVendor  int
+	IdProduct int
+
+	Manufacturer string
+	Product      string
+	SerialNumber string
+
+	// In Mbit/s, such as 480 for high speed
+	Speed int
+}
+
+// Endpoint of an interface's current altsetting
+type Endpoint struct {
+	Address       int
+	Type          string
+	MaxPacketSize int
+	Interval      int
+}
+
+func (ep Endpoint) In() bool { return ep.Address&EndpointIn != 0 }
+
+func readAttr(elem ...string) string {
+	data, _ := os.ReadFile(filepath.Join(elem...))
+	return strings.TrimSpace(string(data))
+}
+
+func readIntAttr(elem ...string) int {
+	v, _ := strconv.Atoi(readAttr(elem...))
+	return v
+}
+
+func readHexAttr(elem ...string) int {
+	v, _ := strconv.ParseInt(readAttr(elem...), 16, 64)
+	return int(v)
+}
+
+// Devices with the given vendor and product IDs, or any when zero, ordered
+// by bus and device number
+func FindDevices(vendor, product int) ([]DeviceInfo, error) {
+	entries, err := os.ReadDir(SysBusUsbDevicesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var devs []DeviceInfo
+
+	for _, ent := range entries {
+		// Interfaces are named `<device>:<config>.<interface>`
+		if strings.ContainsRune(ent.Name(), ':') {
+			continue
+		}
+
+		var (
+			path = filepath.Join(SysBusUsbDevicesPath, ent.Name())
+			d    = DeviceInfo{
+				SysPath:      path,
+				Busnum:       readIntAttr(path, "busnum"),
+				Devnum:       readIntAttr(path, "devnum"),
+				IdVendor:     readHexAttr(path, "idVendor"),
+				IdProduct:    readHexAttr(path, "idProduct"),
+				Manufacturer: readAttr(path, "manufacturer"),
+				Product:      readAttr(path, "product"),
+				SerialNumber: readAttr(path, "serial"),
+				Speed:        readIntAttr(path, "speed"),
+			}
+		)
+
+		if (vendor != 0 && d.IdVendor != vendor) || (product != 0 && d.IdProduct != product) {
+			continue
+		}
+
+		devs = append(devs, d)
+	}
+
+	sort.Slice(devs, func(i, j int) bool {
+		if devs[i].Busnum != devs[j].Busnum {
+			return devs[i].Busnum < devs[j].Busnum
+		}
+		return devs[i].Devnum < devs[j].Devnum
+	})
+
+	return devs, nil
+}
+
+// Device node, such as `/dev/bus/usb/001/002`
+func (d *DeviceInfo) DevPath() string {
+	return filepath.Join(DevBusUsbPath, fmt.Sprintf("%03d", d.Busnum), fmt.Sprintf("%03d", d.Devnum))
+}
+
+func (d *DeviceInfo) Open() (*Device, error) { return OpenDevice(d.DevPath()) }
+
+// Endpoints of an interface in the active configuration, for its current
+// altsetting, ordered by address
+func (d *DeviceInfo) Endpoints(iface int) ([]Endpoint, error) {
+	var (
+		config = readIntAttr(d.SysPath, "bConfigurationValue")
+		path   = fmt.Sprintf("%s:%d.%d", d.SysPath, config, iface)
+	)
+
+	matches, err := filepath.Glob(filepath.Join(path, "ep_*"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var eps []Endpoint
+
+	for _, epPath := range matches {
+		eps = append(eps, Endpoint{
+			Address: readHexAttr(epPath, "bEndpointAddress"),
+			Type:    readAttr(epPath, "type"),
+			// Without the high speed additional transactions bits
+			MaxPacketSize: readHexAttr(epPath, "wMaxPacketSize") & 0x7ff,
+			Interval:      readHexAttr(epPath, "bInterval"),
+		})
+	}
+
+	sort.Slice(eps, func(i, j int) bool { return eps[i].Address < eps[j].Address })
+
+	return eps, nil
+}
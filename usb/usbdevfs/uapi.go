@@ -0,0 +1,40 @@
+package usbdevfs
+
+import "unsafe"
+
+const (
+	_IOC_WRITE = 1
+	_IOC_READ  = 2
+)
+
+func _IOC(dir, nr, size uintptr) uintptr { return dir<<30 | size<<16 | 'U'<<8 | nr }
+
+type usbdevfs_bulktransfer struct {
+	ep      uint32
+	len     uint32
+	timeout uint32 // in milliseconds
+	data    unsafe.Pointer
+}
+
+type usbdevfs_setinterface struct {
+	iface      uint32
+	altsetting uint32
+}
+
+type usbdevfs_ioctl struct {
+	ifno      int32
+	ioctlCode int32
+	data      unsafe.Pointer
+}
+
+var (
+	_USBDEVFS_BULK             = _IOC(_IOC_READ|_IOC_WRITE, 2, unsafe.Sizeof(usbdevfs_bulktransfer{}))
+	_USBDEVFS_SETINTERFACE     = _IOC(_IOC_READ, 4, unsafe.Sizeof(usbdevfs_setinterface{}))
+	_USBDEVFS_SETCONFIGURATION = _IOC(_IOC_READ, 5, 4)
+	_USBDEVFS_CLAIMINTERFACE   = _IOC(_IOC_READ, 15, 4)
+	_USBDEVFS_RELEASEINTERFACE = _IOC(_IOC_READ, 16, 4)
+	_USBDEVFS_IOCTL            = _IOC(_IOC_READ|_IOC_WRITE, 18, unsafe.Sizeof(usbdevfs_ioctl{}))
+	_USBDEVFS_RESET            = _IOC(0, 20, 0)
+	_USBDEVFS_CLEAR_HALT       = _IOC(_IOC_READ, 21, 4)
+	_USBDEVFS_DISCONNECT       = _IOC(0, 22, 0)
+)
@@ -0,0 +1,223 @@
+package gadgetconfig
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// USB Audio Class functions. In the kernel's terms, capture is audio the
+// gadget receives from the host, as a speaker would, and playback is audio
+// it sends to the host, as a microphone would.
+//
+// See <https://docs.kernel.org/usb/gadget-testing.html>
+
+// Limits of the kernel's audio functions
+const (
+	AudioMaxRates = 10
+
+	audioMaxHsBint = 4
+)
+
+// Capture clock synchronisation, UAC2 only
+const (
+	AudioSyncAsync    = "async"
+	AudioSyncAdaptive = "adaptive"
+)
+
+// AudioStream is one direction of an audio function. Fields left at zero
+// keep the kernel's defaults.
+type AudioStream struct {
+	ChannelMask int   `json:"channel_mask,omitempty"`
+	SampleRates []int `json:"sample_rates,omitempty"`
+
+	// In bytes, 1 to 4
+	SampleSize int `json:"sample_size,omitempty"`
+
+	// Feature unit controls
+	Mute   *bool        `json:"mute,omitempty"`
+	Volume *AudioVolume `json:"volume,omitempty"`
+
+	// UAC2 only: high speed bInterval exponent, USB Audio terminal type,
+	// and capture synchronisation
+	HsBint       int    `json:"hs_bint,omitempty"`
+	TerminalType int    `json:"terminal_type,omitempty"`
+	Sync         string `json:"sync,omitempty"`
+}
+
+// AudioVolume control range, in 1/256 dB. A nil range means no volume
+// control.
+type AudioVolume struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+	Res int `json:"res"`
+}
+
+// Steps for the attributes of one direction, whose names begin with
+// `prefix`. Without a stream, the direction is disabled.
+func (s *AudioStream) steps(prefix string) Steps {
+	if s == nil {
+		return Steps{Step{Write, prefix + "chmask", "0"}}
+	}
+
+	var rates = make([]string, len(s.SampleRates))
+	for i, v := range s.SampleRates {
+		rates[i] = strconv.Itoa(v)
+	}
+
+	var steps = Steps{
+		Step{Write, prefix + "chmask", intAttr(s.ChannelMask)},
+		Step{Write, prefix + "srate", strings.Join(rates, ",")},
+		Step{Write, prefix + "ssize", intAttr(s.SampleSize)},
+		Step{Write, prefix + "hs_bint", intAttr(s.HsBint)},
+		Step{Write, prefix + "terminal_type", intAttr(s.TerminalType)},
+		Step{Write, prefix + "sync", s.Sync},
+	}
+
+	if s.Mute != nil {
+		steps.Append(Step{Write, prefix + "mute_present", boolToIntStr(*s.Mute)})
+	}
+
+	if v := s.Volume; v != nil {
+		steps.Extend(Steps{
+			Step{Write, prefix + "volume_present", "1"},
+			Step{Write, prefix + "volume_min", strconv.Itoa(v.Min)},
+			Step{Write, prefix + "volume_max", strconv.Itoa(v.Max)},
+			Step{Write, prefix + "volume_res", strconv.Itoa(v.Res)},
+		})
+	} else {
+		steps.Append(Step{Write, prefix + "volume_present", "0"})
+	}
+
+	return steps
+}
+
+func (s *AudioStream) validate(dir string, uac2 bool) error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+
+	var check = func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(dir+" "+format, args...))
+		}
+	}
+
+	check(s.ChannelMask >= 0, "channel mask 0x%x out of range", s.ChannelMask)
+	check(len(s.SampleRates) <= AudioMaxRates, "more than %d sample rates", AudioMaxRates)
+	check(s.SampleSize >= 0 && s.SampleSize <= 4, "sample size %d must be 1 to 4 bytes", s.SampleSize)
+
+	var seen = make(map[int]bool)
+	for _, v := range s.SampleRates {
+		check(v > 0, "sample rate %d out of range", v)
+		check(!seen[v], "duplicate sample rate %d", v)
+		seen[v] = true
+	}
+
+	if v := s.Volume; v != nil {
+		check(v.Min >= -0x8000 && v.Max <= 0x7fff && v.Min < v.Max, "volume range %d to %d invalid", v.Min, v.Max)
+		check(v.Res > 0 && v.Res <= v.Max-v.Min, "volume resolution %d invalid", v.Res)
+	}
+
+	if uac2 {
+		check(s.HsBint >= 0 && s.HsBint <= audioMaxHsBint, "hs_bint %d out of range", s.HsBint)
+		check(s.TerminalType >= 0 && s.TerminalType <= 0xffff, "terminal type 0x%x out of range", s.TerminalType)
+
+		check(s.Sync == "" || dir == "capture", "sync is for capture only")
+
+		switch s.Sync {
+		case "", AudioSyncAsync, AudioSyncAdaptive:
+		default:
+			check(false, "sync `%s` must be `%s` or `%s`", s.Sync, AudioSyncAsync, AudioSyncAdaptive)
+		}
+	} else {
+		check(s.HsBint == 0 && s.TerminalType == 0 && s.Sync == "", "hs_bint, terminal type and sync are UAC2 only")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Uac1Function
+type Uac1Function struct {
+	Name     string       `json:"name"`
+	Capture  *AudioStream `json:"capture,omitempty"`
+	Playback *AudioStream `json:"playback,omitempty"`
+
+	// Requests queued per direction
+	ReqNumber int `json:"req_number,omitempty"`
+
+	// Interface string, in English only
+	FunctionName string `json:"function_name,omitempty"`
+}
+
+var _ Function = (*Uac1Function)(nil)
+
+func (fn *Uac1Function) GadgetFunctionName() string { return "uac1." + fn.Name }
+
+func (fn *Uac1Function) GadgetFunctionCreate() (steps Steps) {
+	steps.Extend(fn.Capture.steps("c_"))
+	steps.Extend(fn.Playback.steps("p_"))
+	steps.Append(Step{Write, "req_number", intAttr(fn.ReqNumber)})
+	return steps.Append(Step{Write, "function_name", fn.FunctionName})
+}
+
+func (fn *Uac1Function) Validate() error {
+	return validateAudio(fn.Capture, fn.Playback, fn.ReqNumber, false)
+}
+
+// Uac2Function
+type Uac2Function struct {
+	Name     string       `json:"name"`
+	Capture  *AudioStream `json:"capture,omitempty"`
+	Playback *AudioStream `json:"playback,omitempty"`
+
+	// Requests queued per direction, and the most the feedback endpoint may
+	// ask the host to speed up, in parts per million
+	ReqNumber int `json:"req_number,omitempty"`
+	FbMax     int `json:"fb_max,omitempty"`
+
+	// Interface string, in English only
+	FunctionName string `json:"function_name,omitempty"`
+}
+
+var _ Function = (*Uac2Function)(nil)
+
+func (fn *Uac2Function) GadgetFunctionName() string { return "uac2." + fn.Name }
+
+func (fn *Uac2Function) GadgetFunctionCreate() (steps Steps) {
+	steps.Extend(fn.Capture.steps("c_"))
+	steps.Extend(fn.Playback.steps("p_"))
+	steps.Append(Step{Write, "req_number", intAttr(fn.ReqNumber)})
+	steps.Append(Step{Write, "fb_max", intAttr(fn.FbMax)})
+	return steps.Append(Step{Write, "function_name", fn.FunctionName})
+}
+
+func (fn *Uac2Function) Validate() error {
+	var err = validateAudio(fn.Capture, fn.Playback, fn.ReqNumber, true)
+
+	if fn.FbMax < 0 {
+		err = errors.Join(err, fmt.Errorf("fb_max %d out of range", fn.FbMax))
+	}
+
+	return err
+}
+
+func validateAudio(capture, playback *AudioStream, reqNumber int, uac2 bool) error {
+	var errs = []error{
+		capture.validate("capture", uac2),
+		playback.validate("playback", uac2),
+	}
+
+	if capture == nil && playback == nil {
+		errs = append(errs, errors.New("neither capture nor playback enabled"))
+	}
+
+	if reqNumber < 0 {
+		errs = append(errs, fmt.Errorf("req_number %d out of range", reqNumber))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package gadgetconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Device class codes
+const (
+	ClassPerInterface = 0x00
+	ClassComm         = 0x02
+	ClassHid          = 0x03
+	ClassMassStorage  = 0x08
+	ClassVendor       = 0xff
+
+	// Composite devices with Interface Association Descriptors, as
+	// Windows requires to bind drivers to multi-interface functions
+	ClassMisc      = 0xef
+	SubClassCommon = 0x02
+	ProtocolIad    = 0x01
+)
+
+// Config bmAttributes bits
+const (
+	ConfigAttrOne          = 0x80
+	ConfigAttrSelfPowered  = 0x40
+	ConfigAttrRemoteWakeup = 0x20
+)
+
+// USB 3 limit on config MaxPower, in mA
+const MaxPowerLimit = 900
+
+var configNameRe = regexp.MustCompile(`^[^./]+\.[0-9]+$`)
+
+// Set the device class to announce Interface Association Descriptors
+func (g *Gadget) UseIad() {
+	g.DeviceClass = ClassMisc
+	g.DeviceSubClass = SubClassCommon
+	g.DeviceProtocol = ProtocolIad
+}
+
+func (c *Config) attributes() int {
+	var attrs = ConfigAttrOne
+
+	if c.SelfPowered {
+		attrs |= ConfigAttrSelfPowered
+	}
+
+	if c.RemoteWakeup {
+		attrs |= ConfigAttrRemoteWakeup
+	}
+
+	return attrs
+}
+
+// Zero values are left unwritten, leaving the kernel's default
+func hexAttr(v, digits int) string {
+	if v == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%0*x", digits, v)
+}
+
+func intAttr(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func isBcd(v int) bool {
+	for ; v > 0; v >>= 4 {
+		if v&0xf > 9 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check the definition before creating it, including any function that has
+// a Validate method
+func (g *Gadget) Validate() error {
+	var errs []error
+
+	var check = func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// The name only places the gadget when it has no path of its own
+	check((g.Name != "" || g.GadgetPath != "") && !strings.ContainsRune(g.Name, '/'), "bad gadget name `%s`", g.Name)
+	check(g.IdVendor >= 0 && g.IdVendor <= 0xffff, "idVendor 0x%x out of range", g.IdVendor)
+	check(g.IdProduct >= 0 && g.IdProduct <= 0xffff, "idProduct 0x%x out of range", g.IdProduct)
+	check(g.BcdUSB >= 0 && g.BcdUSB <= 0xffff && isBcd(g.BcdUSB), "bcdUSB 0x%x is not BCD", g.BcdUSB)
+	check(g.BcdDevice >= 0 && g.BcdDevice <= 0xffff && isBcd(g.BcdDevice), "bcdDevice 0x%x is not BCD", g.BcdDevice)
+	check(g.DeviceClass >= 0 && g.DeviceClass <= 0xff, "bDeviceClass 0x%x out of range", g.DeviceClass)
+	check(g.DeviceSubClass >= 0 && g.DeviceSubClass <= 0xff, "bDeviceSubClass 0x%x out of range", g.DeviceSubClass)
+	check(g.DeviceProtocol >= 0 && g.DeviceProtocol <= 0xff, "bDeviceProtocol 0x%x out of range", g.DeviceProtocol)
+
+	switch g.MaxPacketSize0 {
+	case 0, 8, 16, 32, 64:
+	default:
+		check(false, "bMaxPacketSize0 %d must be 8, 16, 32 or 64", g.MaxPacketSize0)
+	}
+
+	if g.DeviceClass == ClassMisc && g.DeviceSubClass == SubClassCommon {
+		check(g.DeviceProtocol == ProtocolIad, "class 0xef/0x02 requires protocol 0x01 for IAD")
+	}
+
+	if err := g.validateStrings(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if g.OsDesc != nil {
+		if err := g.OsDesc.validate(g); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var configs = make(map[string]bool)
+
+	for i := range g.Configs {
+		var c = &g.Configs[i]
+
+		check(configNameRe.MatchString(c.Name), "config name `%s` must be of the form `<label>.<number>`", c.Name)
+		check(!configs[c.Name], "duplicate config `%s`", c.Name)
+		if v := c.MaxPower; v != nil {
+			check(*v >= 0 && *v <= MaxPowerLimit, "config `%s` MaxPower %d mA out of range", c.Name, *v)
+		}
+
+		configs[c.Name] = true
+
+		for _, fn := range c.Functions {
+			if v, ok := fn.(interface{ Validate() error }); ok {
+				if err := v.Validate(); err != nil {
+					errs = append(errs, fmt.Errorf("function `%s`: %w", fn.GadgetFunctionName(), err))
+				}
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("gadget `%s`: %w", orDefault(g.Name, g.GadgetPath), err)
+	}
+
+	return nil
+}
@@ -1,7 +1,14 @@
 package gadgetconfig
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 func boolToIntStr(b bool) string {
@@ -18,22 +25,69 @@ type Function interface {
 	GadgetFunctionCreate() Steps
 }
 
+// Constructors for each known function type, keyed by the configfs type
+// name, which is the part of the function directory name before the `.`
+var functionTypes = map[string]func() Function{
+	"acm":          func() Function { return new(AcmFunction) },
+	"eem":          func() Function { return new(EemFunction) },
+	"ncm":          func() Function { return new(NcmFunction) },
+	"ecm":          func() Function { return new(EcmFunction) },
+	"geth":         func() Function { return new(EcmSubsetFunction) },
+	"rndis":        func() Function { return new(RndisFunction) },
+	"gser":         func() Function { return new(SerialFunction) },
+	"obex":         func() Function { return new(ObexFunction) },
+	"uac1":         func() Function { return new(Uac1Function) },
+	"uac2":         func() Function { return new(Uac2Function) },
+	"uvc":          func() Function { return new(UvcFunction) },
+	"midi":         func() Function { return new(MidiFunction) },
+	"printer":      func() Function { return new(PrinterFunction) },
+	"hid":          func() Function { return new(HidFunction) },
+	"mass_storage": func() Function { return new(MassStorageFunction) },
+	"Loopback":     func() Function { return new(LoopbackFunction) },
+	"SourceSink":   func() Function { return new(SourceSinkFunction) },
+}
+
+// Make a function type known, for types defined outside this package
+func RegisterFunctionType(typ string, newFn func() Function) { functionTypes[typ] = newFn }
+
+// Allocate an empty function of the given type, or a GenericFunction if the
+// type is unknown
+func NewFunction(typ string) Function {
+	if newFn, ok := functionTypes[typ]; ok {
+		return newFn()
+	}
+
+	return &GenericFunction{Type: typ}
+}
+
+// Type name of a function, such as `ncm`
+func FunctionType(fn Function) string {
+	typ, _, _ := strings.Cut(fn.GadgetFunctionName(), ".")
+	return typ
+}
+
 // AcmFunction
 type AcmFunction struct {
-	Name string
+	Name    string `json:"name"`
+	Console bool   `json:"console,omitempty"`
 }
 
 var _ Function = (*AcmFunction)(nil)
 
 func (fn *AcmFunction) GadgetFunctionName() string { return "acm." + fn.Name }
 
-func (fn *AcmFunction) GadgetFunctionCreate() Steps { return nil }
+func (fn *AcmFunction) GadgetFunctionCreate() Steps {
+	return Steps{
+		Step{Write, "console", consoleAttr(fn.Console)},
+	}
+}
 
 // EemFunction
 type EemFunction struct {
-	Name     string
-	DevAddr  string
-	HostAddr string
+	Name     string `json:"name"`
+	DevAddr  string `json:"dev_addr,omitempty"`
+	HostAddr string `json:"host_addr,omitempty"`
+	Qmult    int    `json:"qmult,omitempty"`
 }
 
 var _ Function = (*EemFunction)(nil)
@@ -41,12 +95,11 @@ var _ Function = (*EemFunction)(nil)
 func (fn *EemFunction) GadgetFunctionName() string { return "eem." + fn.Name }
 
 func (fn *EemFunction) GadgetFunctionCreate() Steps {
-	return Steps{
-		Step{Write, "dev_addr", fn.DevAddr},
-		Step{Write, "host_addr", fn.HostAddr},
-	}
+	return etherSteps(fn.DevAddr, fn.HostAddr, fn.Qmult)
 }
 
+func (fn *EemFunction) Validate() error { return validateEther(fn.DevAddr, fn.HostAddr, fn.Qmult) }
+
 func (fn *EemFunction) Ifname(g *Gadget) string {
 	data, _ := g.ReadConfigfsFile("functions", fn.GadgetFunctionName(), "ifname")
 	return data
@@ -54,9 +107,13 @@ func (fn *EemFunction) Ifname(g *Gadget) string {
 
 // NcmFunction
 type NcmFunction struct {
-	Name     string
-	DevAddr  string
-	HostAddr string
+	Name     string `json:"name"`
+	DevAddr  string `json:"dev_addr,omitempty"`
+	HostAddr string `json:"host_addr,omitempty"`
+	Qmult    int    `json:"qmult,omitempty"`
+
+	// Interface `ncm`, with compatible ID `WINNCM` for the Windows driver
+	OsDesc []OsDescInterface `json:"os_desc,omitempty"`
 }
 
 var _ Function = (*NcmFunction)(nil)
@@ -64,10 +121,12 @@ var _ Function = (*NcmFunction)(nil)
 func (fn *NcmFunction) GadgetFunctionName() string { return "ncm." + fn.Name }
 
 func (fn *NcmFunction) GadgetFunctionCreate() Steps {
-	return Steps{
-		Step{Write, "dev_addr", fn.DevAddr},
-		Step{Write, "host_addr", fn.HostAddr},
-	}
+	var steps = etherSteps(fn.DevAddr, fn.HostAddr, fn.Qmult)
+	return steps.Extend(osDescInterfaceSteps(fn.OsDesc))
+}
+
+func (fn *NcmFunction) Validate() error {
+	return errors.Join(validateEther(fn.DevAddr, fn.HostAddr, fn.Qmult), validateOsDescInterfaces(fn.OsDesc))
 }
 
 func (fn *NcmFunction) Ifname(g *Gadget) string {
@@ -75,13 +134,185 @@ func (fn *NcmFunction) Ifname(g *Gadget) string {
 	return data
 }
 
+// EcmFunction
+type EcmFunction struct {
+	Name     string `json:"name"`
+	DevAddr  string `json:"dev_addr,omitempty"`
+	HostAddr string `json:"host_addr,omitempty"`
+	Qmult    int    `json:"qmult,omitempty"`
+}
+
+var _ Function = (*EcmFunction)(nil)
+
+func (fn *EcmFunction) GadgetFunctionName() string { return "ecm." + fn.Name }
+
+func (fn *EcmFunction) GadgetFunctionCreate() Steps {
+	return etherSteps(fn.DevAddr, fn.HostAddr, fn.Qmult)
+}
+
+func (fn *EcmFunction) Validate() error { return validateEther(fn.DevAddr, fn.HostAddr, fn.Qmult) }
+
+func (fn *EcmFunction) Ifname(g *Gadget) string {
+	data, _ := g.ReadConfigfsFile("functions", fn.GadgetFunctionName(), "ifname")
+	return data
+}
+
+// EcmSubsetFunction is the CDC subset, or `geth`, for hosts without full
+// ECM support
+type EcmSubsetFunction struct {
+	Name     string `json:"name"`
+	DevAddr  string `json:"dev_addr,omitempty"`
+	HostAddr string `json:"host_addr,omitempty"`
+	Qmult    int    `json:"qmult,omitempty"`
+}
+
+var _ Function = (*EcmSubsetFunction)(nil)
+
+func (fn *EcmSubsetFunction) GadgetFunctionName() string { return "geth." + fn.Name }
+
+func (fn *EcmSubsetFunction) GadgetFunctionCreate() Steps {
+	return etherSteps(fn.DevAddr, fn.HostAddr, fn.Qmult)
+}
+
+func (fn *EcmSubsetFunction) Validate() error {
+	return validateEther(fn.DevAddr, fn.HostAddr, fn.Qmult)
+}
+
+func (fn *EcmSubsetFunction) Ifname(g *Gadget) string {
+	data, _ := g.ReadConfigfsFile("functions", fn.GadgetFunctionName(), "ifname")
+	return data
+}
+
+// RndisFunction
+type RndisFunction struct {
+	Name     string `json:"name"`
+	DevAddr  string `json:"dev_addr,omitempty"`
+	HostAddr string `json:"host_addr,omitempty"`
+	Qmult    int    `json:"qmult,omitempty"`
+
+	// Interface class codes, left at the kernel's defaults when zero. Hosts
+	// without OS descriptors recognise class 0xef, subclass 0x04, protocol
+	// 0x01 as RNDIS.
+	Class    int `json:"class,omitempty"`
+	Subclass int `json:"subclass,omitempty"`
+	Protocol int `json:"protocol,omitempty"`
+
+	// Interface `rndis`, which the kernel gives compatible ID `RNDIS` and
+	// sub-compatible ID `5162001`
+	OsDesc []OsDescInterface `json:"os_desc,omitempty"`
+}
+
+var _ Function = (*RndisFunction)(nil)
+
+func (fn *RndisFunction) GadgetFunctionName() string { return "rndis." + fn.Name }
+
+func (fn *RndisFunction) GadgetFunctionCreate() Steps {
+	var steps = etherSteps(fn.DevAddr, fn.HostAddr, fn.Qmult)
+
+	steps.Extend(Steps{
+		Step{Write, "class", hexAttr(fn.Class, 2)},
+		Step{Write, "subclass", hexAttr(fn.Subclass, 2)},
+		Step{Write, "protocol", hexAttr(fn.Protocol, 2)},
+	})
+
+	return steps.Extend(osDescInterfaceSteps(fn.OsDesc))
+}
+
+func (fn *RndisFunction) Validate() error {
+	var errs = []error{
+		validateEther(fn.DevAddr, fn.HostAddr, fn.Qmult),
+		validateOsDescInterfaces(fn.OsDesc),
+	}
+
+	for _, v := range []int{fn.Class, fn.Subclass, fn.Protocol} {
+		if v < 0 || v > 0xff {
+			errs = append(errs, fmt.Errorf("interface class code 0x%x out of range", v))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (fn *RndisFunction) Ifname(g *Gadget) string {
+	data, _ := g.ReadConfigfsFile("functions", fn.GadgetFunctionName(), "ifname")
+	return data
+}
+
+// Attributes common to the Ethernet functions. Addresses are written in the
+// lowercase form the kernel reads them back in, so that they compare equal
+// when reconciling.
+func etherSteps(devAddr, hostAddr string, qmult int) Steps {
+	return Steps{
+		Step{Write, "dev_addr", etherAttr(devAddr)},
+		Step{Write, "host_addr", etherAttr(hostAddr)},
+		Step{Write, "qmult", intAttr(qmult)},
+	}
+}
+
+// Six colon separated bytes, as the kernel parses them
+var etherAddrRe = regexp.MustCompile(`^[0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}$`)
+
+// A unicast, non-zero address, as the kernel accepts for dev_addr and
+// host_addr
+func parseEtherAddr(addr string) (net.HardwareAddr, error) {
+	if !etherAddrRe.MatchString(addr) {
+		return nil, fmt.Errorf("bad Ethernet address `%s`, must be six colon separated bytes", addr)
+	}
+
+	hw, err := net.ParseMAC(addr)
+	if err != nil {
+		return nil, fmt.Errorf("bad Ethernet address `%s`: %w", addr, err)
+	}
+
+	if hw[0]&0x01 != 0 {
+		return nil, fmt.Errorf("multicast Ethernet address `%s`", addr)
+	}
+
+	if bytes.Equal(hw, make(net.HardwareAddr, len(hw))) {
+		return nil, fmt.Errorf("all-zero Ethernet address `%s`", addr)
+	}
+
+	return hw, nil
+}
+
+func etherAttr(addr string) string {
+	if hw, err := parseEtherAddr(addr); err == nil {
+		return hw.String()
+	}
+	return addr
+}
+
+func validateEther(devAddr, hostAddr string, qmult int) error {
+	var errs []error
+
+	for _, addr := range []string{devAddr, hostAddr} {
+		if addr == "" {
+			continue
+		}
+
+		if _, err := parseEtherAddr(addr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if qmult < 0 {
+		errs = append(errs, fmt.Errorf("qmult %d out of range", qmult))
+	}
+
+	return errors.Join(errs...)
+}
+
 // HidFunction
 type HidFunction struct {
-	Name         string
-	Protocol     int
-	Subclass     int
-	ReportLength int
-	Descriptor   []byte
+	Name         string `json:"name"`
+	Protocol     int    `json:"protocol"`
+	Subclass     int    `json:"subclass"`
+	ReportLength int    `json:"report_length"`
+	Descriptor   []byte `json:"descriptor,omitempty"`
+
+	// Report descriptor file, relative to the gadget file, used when
+	// Descriptor is empty
+	DescriptorFile string `json:"descriptor_file,omitempty"`
 }
 
 var _ Function = (*HidFunction)(nil)
@@ -99,8 +330,12 @@ func (fn *HidFunction) GadgetFunctionCreate() Steps {
 
 // MassStorageFunction
 type MassStorageFunction struct {
-	Name string
-	Luns []MassStorageLun
+	Name string           `json:"name"`
+	Luns []MassStorageLun `json:"luns"`
+
+	// Whether to halt bulk endpoints to signal errors, left at the kernel's
+	// default when nil
+	Stall *bool `json:"stall,omitempty"`
 }
 
 var _ Function = (*MassStorageFunction)(nil)
@@ -108,6 +343,10 @@ var _ Function = (*MassStorageFunction)(nil)
 func (fn *MassStorageFunction) GadgetFunctionName() string { return "mass_storage." + fn.Name }
 
 func (fn *MassStorageFunction) GadgetFunctionCreate() (steps Steps) {
+	if fn.Stall != nil {
+		steps.Append(Step{Write, "stall", boolToIntStr(*fn.Stall)})
+	}
+
 	for _, lun := range fn.Luns {
 		var (
 			prefix = "lun." + lun.Name
@@ -127,16 +366,55 @@ func (fn *MassStorageFunction) GadgetFunctionCreate() (steps Steps) {
 
 // MassStorageLun
 type MassStorageLun struct {
-	Name      string
-	File      string
-	Removable bool
-	Cdrom     bool
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Removable bool   `json:"removable,omitempty"`
+	Cdrom     bool   `json:"cdrom,omitempty"`
+	ReadOnly  bool   `json:"ro,omitempty"`
+	NoFua     bool   `json:"nofua,omitempty"`
+
+	// SCSI INQUIRY vendor, product and revision, left at the kernel's
+	// default when empty
+	InquiryString string `json:"inquiry_string,omitempty"`
 }
 
+// The backing file is opened as soon as it is written, after which the
+// kernel refuses changes to `ro` and `cdrom`, so it goes last. CD-ROM LUNs
+// are always read-only.
 func (lun *MassStorageLun) lunCreate() Steps {
 	return Steps{
-		Step{Write, "file", lun.File},
+		Step{Write, "ro", boolToIntStr(lun.ReadOnly || lun.Cdrom)},
 		Step{Write, "removable", boolToIntStr(lun.Removable)},
 		Step{Write, "cdrom", boolToIntStr(lun.Cdrom)},
+		Step{Write, "nofua", boolToIntStr(lun.NoFua)},
+		Step{Write, "inquiry_string", lun.InquiryString},
+		Step{Write, "file", lun.File},
 	}
 }
+
+// GenericFunction is any function type without a dedicated definition, with
+// its attributes written in name order
+type GenericFunction struct {
+	Type  string            `json:"-"`
+	Name  string            `json:"name"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+var _ Function = (*GenericFunction)(nil)
+
+func (fn *GenericFunction) GadgetFunctionName() string { return fn.Type + "." + fn.Name }
+
+func (fn *GenericFunction) GadgetFunctionCreate() (steps Steps) {
+	var names = make([]string, 0, len(fn.Attrs))
+	for name := range fn.Attrs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		steps.Append(Step{Write, name, fn.Attrs[name]})
+	}
+
+	return
+}
@@ -5,13 +5,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 const (
 	GadgetConfigBasePath = "/sys/kernel/config/usb_gadget"
 	UdcPathGlob          = "/sys/class/udc/*"
-	StrEnglish           = "0x409"
+
+	// Deprecated: use LangEnglish
+	StrEnglish = "0x409"
 )
 
 func FindUdc() []string {
@@ -33,15 +36,30 @@ func FindUdc() []string {
 
 // Gadget
 type Gadget struct {
-	Name         string
-	GadgetPath   string
-	IdVendor     int
-	IdProduct    int
-	SerialNumber string
-	Manufacturer string
-	Product      string
-	UDC          string
-	Configs      []Config
+	Name         string `json:"name"`
+	GadgetPath   string `json:"gadget_path,omitempty"`
+	IdVendor     int    `json:"id_vendor"`
+	IdProduct    int    `json:"id_product"`
+	SerialNumber string `json:"serial_number,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+
+	// Strings in other languages, with the fields above as English
+	Strings map[LangId]GadgetStrings `json:"strings,omitempty"`
+
+	// Device descriptor fields, left at the kernel's defaults when zero
+	BcdUSB         int `json:"bcd_usb,omitempty"`
+	BcdDevice      int `json:"bcd_device,omitempty"`
+	DeviceClass    int `json:"device_class,omitempty"`
+	DeviceSubClass int `json:"device_subclass,omitempty"`
+	DeviceProtocol int `json:"device_protocol,omitempty"`
+	MaxPacketSize0 int `json:"max_packet_size0,omitempty"`
+
+	// Microsoft OS descriptors, not used when nil
+	OsDesc *OsDesc `json:"os_desc,omitempty"`
+
+	UDC     string   `json:"udc,omitempty"`
+	Configs []Config `json:"configs"`
 }
 
 func (g *Gadget) gadgetPath() string {
@@ -56,66 +74,118 @@ func (g *Gadget) Exists() bool {
 	return !os.IsNotExist(err)
 }
 
-func (g *Gadget) Create() error           { return g.CreateSteps().Run() }
+func (g *Gadget) Create() error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	return g.CreateSteps().RunTransaction()
+}
+
 func (g *Gadget) Remove() error           { return g.RemoveSteps().Run() }
 func (g *Gadget) ShellCreate() ShellSteps { return g.CreateSteps().ShellArgs() }
 func (g *Gadget) ShellRemove() ShellSteps { return g.RemoveSteps().ShellArgs() }
 
-func (g *Gadget) RemoveSteps() Steps { return g.CreateSteps().Undo().Reverse() }
+// Discovered from configfs when the gadget exists, or else derived from the
+// definition, as for a script
+func (g *Gadget) RemoveSteps() Steps {
+	if g.Exists() {
+		if steps, err := TeardownSteps(g.gadgetPath()); err == nil {
+			return steps
+		}
+	}
+
+	return g.CreateSteps().Undo().Reverse()
+}
 
 func (g *Gadget) CreateSteps() (steps Steps) {
-	steps = Steps{
-		Step{Mkdir, "", ""},
-		Step{Write, "idVendor", fmt.Sprintf("0x%04x", g.IdVendor)},
-		Step{Write, "idProduct", fmt.Sprintf("0x%04x", g.IdProduct)},
+	var created = make(map[string]bool)
 
-		Step{Mkdir, "strings/" + StrEnglish, ""},
-		Step{Write, "strings/" + StrEnglish + "/serialnumber", g.SerialNumber},
-		Step{Write, "strings/" + StrEnglish + "/manufacturer", g.Manufacturer},
-		Step{Write, "strings/" + StrEnglish + "/product", g.Product},
-	}
+	steps.Extend(g.deviceSteps())
 
 	for i := range g.Configs {
-		var (
-			c           = &g.Configs[i]
-			configPath  = "configs/" + c.Name
-			configSteps = Steps{
-				Step{Comment, fmt.Sprintf("config `%s`", c.Name), ""},
-				Step{Mkdir, "", ""},
-				Step{Mkdir, "strings/" + StrEnglish, ""},
-				Step{Write, "strings/" + StrEnglish + "/configuration", c.Configuration},
-			}
-		)
+		var c = &g.Configs[i]
 
-		configSteps.PrependPath(configPath)
-		steps.Extend(configSteps)
+		steps.Extend(c.configSteps())
 
 		for _, fn := range c.Functions {
-			var (
-				name    = fn.GadgetFunctionName()
-				fnPath  = "functions/" + name
-				fnSteps = Steps{
-					Step{Comment, fmt.Sprintf("config `%s`, function `%s`", c.Name, name), ""},
-					Step{Mkdir, "", ""},
-				}
-			)
-
-			fnSteps.Extend(fn.GadgetFunctionCreate())
-			fnSteps.PrependPath(fnPath)
-			steps.Extend(fnSteps)
-
-			// Attach function to configuration
-			steps.Append(Step{Symlink, fnPath, configPath + "/" + name})
+			// Functions shared between configs are only created once
+			if name := fn.GadgetFunctionName(); !created[name] {
+				created[name] = true
+				steps.Extend(functionSteps(c, fn))
+			}
+
+			steps.Append(linkStep(c, fn))
 		}
 	}
 
+	steps.Extend(g.osDescLinkSteps())
+
 	if v := g.UDC; v != "" {
-		steps.Append(Step{Write, "UDC", v})
+		steps.Append(Step{Bind, "UDC", v})
 	}
 
 	return steps.PrependPath(g.gadgetPath())
 }
 
+// Steps for the gadget directory and device level attributes, relative to
+// the gadget directory. Descriptor fields are written in descriptor order,
+// and in the format configfs reads them back in.
+func (g *Gadget) deviceSteps() Steps {
+	var steps = Steps{
+		Step{Mkdir, "", ""},
+		Step{Write, "bcdUSB", hexAttr(g.BcdUSB, 4)},
+		Step{Write, "bDeviceClass", hexAttr(g.DeviceClass, 2)},
+		Step{Write, "bDeviceSubClass", hexAttr(g.DeviceSubClass, 2)},
+		Step{Write, "bDeviceProtocol", hexAttr(g.DeviceProtocol, 2)},
+		Step{Write, "bMaxPacketSize0", hexAttr(g.MaxPacketSize0, 2)},
+		Step{Write, "idVendor", fmt.Sprintf("0x%04x", g.IdVendor)},
+		Step{Write, "idProduct", fmt.Sprintf("0x%04x", g.IdProduct)},
+		Step{Write, "bcdDevice", hexAttr(g.BcdDevice, 4)},
+	}
+
+	steps.Extend(g.stringsSteps())
+	return steps.Extend(g.osDescSteps())
+}
+
+func (c *Config) configPath() string { return "configs/" + c.Name }
+
+func (c *Config) configSteps() Steps {
+	var steps = Steps{
+		Step{Comment, fmt.Sprintf("config `%s`", c.Name), ""},
+		Step{Mkdir, "", ""},
+		Step{Write, "bmAttributes", fmt.Sprintf("0x%02x", c.attributes())},
+	}
+
+	if c.MaxPower != nil {
+		steps.Append(Step{Write, "MaxPower", strconv.Itoa(*c.MaxPower)})
+	}
+
+	steps.Extend(c.stringsSteps())
+	return steps.PrependPath(c.configPath())
+}
+
+func functionPath(fn Function) string { return "functions/" + fn.GadgetFunctionName() }
+
+func functionSteps(c *Config, fn Function) Steps {
+	var (
+		name  = fn.GadgetFunctionName()
+		steps = Steps{
+			Step{Comment, fmt.Sprintf("config `%s`, function `%s`", c.Name, name), ""},
+			Step{Mkdir, "", ""},
+		}
+	)
+
+	steps.Extend(fn.GadgetFunctionCreate())
+
+	return steps.PrependPath(functionPath(fn))
+}
+
+// Attach function to configuration
+func linkStep(c *Config, fn Function) Step {
+	return Step{Symlink, functionPath(fn), c.configPath() + "/" + fn.GadgetFunctionName()}
+}
+
 func (g *Gadget) ReadConfigfsFile(elem ...string) (string, error) {
 	var path = filepath.Join(g.gadgetPath(), filepath.Join(elem...))
 	if buf, err := ioutil.ReadFile(path); err != nil {
@@ -127,7 +197,16 @@ func (g *Gadget) ReadConfigfsFile(elem ...string) (string, error) {
 
 // Config
 type Config struct {
-	Name          string
-	Configuration string
-	Functions     []Function
+	Name          string `json:"name"`
+	Configuration string `json:"configuration,omitempty"`
+
+	// Configuration in other languages, with the field above as English
+	Strings map[LangId]ConfigStrings `json:"strings,omitempty"`
+
+	// In mA, left at the kernel's default when nil
+	MaxPower     *int `json:"max_power,omitempty"`
+	SelfPowered  bool `json:"self_powered,omitempty"`
+	RemoteWakeup bool `json:"remote_wakeup,omitempty"`
+
+	Functions []Function `json:"functions"`
 }
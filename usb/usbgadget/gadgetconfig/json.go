@@ -0,0 +1,277 @@
+package gadgetconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// JSON gadget definitions. Function lists carry a `"type"` member naming
+// the configfs function type, and vendor and product IDs and BCD versions
+// may be given as numbers or as strings such as `"0x1d6b"`, as may the OS
+// descriptor vendor code.
+
+// hexId
+type hexId int
+
+func (id hexId) MarshalJSON() ([]byte, error) { return json.Marshal(fmt.Sprintf("0x%04x", int(id))) }
+
+func (id *hexId) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("gadgetconfig: bad ID %s", data)
+		}
+
+		*id = hexId(n)
+		return nil
+	}
+
+	n, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return fmt.Errorf("gadgetconfig: bad ID `%s`: %w", s, err)
+	}
+
+	*id = hexId(n)
+	return nil
+}
+
+// hexByte
+type hexByte int
+
+func (b hexByte) MarshalJSON() ([]byte, error) { return json.Marshal(fmt.Sprintf("0x%02x", int(b))) }
+
+func (b *hexByte) UnmarshalJSON(data []byte) error {
+	var id hexId
+	if err := id.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	*b = hexByte(id)
+	return nil
+}
+
+// Shadows the ID fields, so the rest of Gadget keeps default handling
+type gadgetJSON struct {
+	*gadgetAlias
+	IdVendor  hexId `json:"id_vendor"`
+	IdProduct hexId `json:"id_product"`
+	BcdUSB    hexId `json:"bcd_usb,omitempty"`
+	BcdDevice hexId `json:"bcd_device,omitempty"`
+}
+
+type gadgetAlias Gadget
+
+func (g Gadget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(gadgetJSON{
+		gadgetAlias: (*gadgetAlias)(&g),
+		IdVendor:    hexId(g.IdVendor),
+		IdProduct:   hexId(g.IdProduct),
+		BcdUSB:      hexId(g.BcdUSB),
+		BcdDevice:   hexId(g.BcdDevice),
+	})
+}
+
+func (g *Gadget) UnmarshalJSON(data []byte) error {
+	var v = gadgetJSON{gadgetAlias: (*gadgetAlias)(g)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	g.IdVendor = int(v.IdVendor)
+	g.IdProduct = int(v.IdProduct)
+	g.BcdUSB = int(v.BcdUSB)
+	g.BcdDevice = int(v.BcdDevice)
+
+	return nil
+}
+
+type configAlias Config
+
+type configJSON struct {
+	*configAlias
+	Functions []json.RawMessage `json:"functions"`
+}
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	var v = configJSON{
+		configAlias: (*configAlias)(&c),
+		Functions:   make([]json.RawMessage, 0, len(c.Functions)),
+	}
+
+	for _, fn := range c.Functions {
+		data, err := MarshalFunction(fn)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Functions = append(v.Functions, data)
+	}
+
+	return json.Marshal(v)
+}
+
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var v = configJSON{configAlias: (*configAlias)(c)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	c.Functions = nil
+
+	for i, raw := range v.Functions {
+		fn, err := UnmarshalFunction(raw)
+		if err != nil {
+			return fmt.Errorf("config `%s` function %d: %w", c.Name, i, err)
+		}
+
+		c.Functions = append(c.Functions, fn)
+	}
+
+	return nil
+}
+
+// Encode a function as a JSON object with its `"type"`
+func MarshalFunction(fn Function) ([]byte, error) {
+	data, err := json.Marshal(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("gadgetconfig: function `%s` does not encode as an object", fn.GadgetFunctionName())
+	}
+
+	typ, err := json.Marshal(FunctionType(fn))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":`)
+	buf.Write(typ)
+
+	if !bytes.Equal(data, []byte("{}")) {
+		buf.WriteByte(',')
+	}
+
+	buf.Write(data[1:])
+
+	return buf.Bytes(), nil
+}
+
+// Decode a function object, using its `"type"` to choose the Go type
+func UnmarshalFunction(data []byte) (Function, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+
+	if head.Type == "" {
+		return nil, fmt.Errorf("gadgetconfig: function missing `type`")
+	}
+
+	var fn = NewFunction(head.Type)
+
+	if err := json.Unmarshal(data, fn); err != nil {
+		return nil, fmt.Errorf("gadgetconfig: %s function: %w", head.Type, err)
+	}
+
+	return fn, nil
+}
+
+// Functions that refer to other files, such as HidFunction.DescriptorFile,
+// resolve them relative to the directory of the gadget file
+type fileResolver interface {
+	resolveFiles(dir string) error
+}
+
+func (fn *HidFunction) resolveFiles(dir string) error {
+	if len(fn.Descriptor) > 0 || fn.DescriptorFile == "" {
+		return nil
+	}
+
+	var path = fn.DescriptorFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("hid function `%s`: %w", fn.Name, err)
+	}
+
+	fn.Descriptor = data
+	return nil
+}
+
+// Read a JSON gadget definition
+func ReadGadgetFile(path string) (*Gadget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var g Gadget
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var dir = filepath.Dir(path)
+
+	for _, c := range g.Configs {
+		for _, fn := range c.Functions {
+			if r, ok := fn.(fileResolver); ok {
+				if err := r.resolveFiles(dir); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+		}
+	}
+
+	return &g, nil
+}
+
+// Write a gadget definition as indented JSON
+func WriteGadgetFile(path string, g *Gadget) error {
+	data, err := json.MarshalIndent(g, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+type osDescAlias OsDesc
+
+type osDescJSON struct {
+	*osDescAlias
+	VendorCode hexByte `json:"vendor_code,omitempty"`
+}
+
+func (d OsDesc) MarshalJSON() ([]byte, error) {
+	return json.Marshal(osDescJSON{
+		osDescAlias: (*osDescAlias)(&d),
+		VendorCode:  hexByte(d.VendorCode),
+	})
+}
+
+func (d *OsDesc) UnmarshalJSON(data []byte) error {
+	var v = osDescJSON{osDescAlias: (*osDescAlias)(d)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	d.VendorCode = int(v.VendorCode)
+	return nil
+}
@@ -0,0 +1,413 @@
+package gadgetconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FunctionLoader is implemented by functions that can read their attributes
+// back from their configfs directory
+type FunctionLoader interface {
+	GadgetFunctionLoad(path string) error
+}
+
+// Reconstruct a gadget from its configfs state. Only functions linked into
+// a config are included, as Gadget has nowhere to hold the others.
+func Load(name string) (*Gadget, error) {
+	return LoadPath(filepath.Join(GadgetConfigBasePath, name))
+}
+
+func LoadPath(path string) (*Gadget, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	var g = Gadget{
+		Name:      filepath.Base(path),
+		IdVendor:  readIntAttr(path, "idVendor"),
+		IdProduct: readIntAttr(path, "idProduct"),
+		UDC:       readAttr(path, "UDC"),
+
+		BcdUSB:         readIntAttr(path, "bcdUSB"),
+		BcdDevice:      readIntAttr(path, "bcdDevice"),
+		DeviceClass:    readIntAttr(path, "bDeviceClass"),
+		DeviceSubClass: readIntAttr(path, "bDeviceSubClass"),
+		DeviceProtocol: readIntAttr(path, "bDeviceProtocol"),
+		MaxPacketSize0: readIntAttr(path, "bMaxPacketSize0"),
+	}
+
+	g.stringsLoad(path)
+	g.osDescLoad(path)
+
+	if filepath.Dir(path) != GadgetConfigBasePath {
+		g.GadgetPath = path
+	}
+
+	var functions = make(map[string]Function)
+
+	configs, err := os.ReadDir(filepath.Join(path, "configs"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ent := range configs {
+		if !ent.IsDir() {
+			continue
+		}
+
+		var (
+			configPath = filepath.Join(path, "configs", ent.Name())
+			c          = Config{Name: ent.Name()}
+			attrs      = readIntAttr(configPath, "bmAttributes")
+			maxPower   = readIntAttr(configPath, "MaxPower")
+		)
+
+		c.MaxPower = &maxPower
+
+		c.stringsLoad(configPath)
+		c.SelfPowered = attrs&ConfigAttrSelfPowered != 0
+		c.RemoteWakeup = attrs&ConfigAttrRemoteWakeup != 0
+
+		links, err := os.ReadDir(configPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			if link.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+
+			target, err := os.Readlink(filepath.Join(configPath, link.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			var fnName = filepath.Base(target)
+
+			fn, ok := functions[fnName]
+			if !ok {
+				if fn, err = loadFunction(filepath.Join(path, "functions", fnName)); err != nil {
+					return nil, err
+				}
+
+				functions[fnName] = fn
+			}
+
+			c.Functions = append(c.Functions, fn)
+		}
+
+		g.Configs = append(g.Configs, c)
+	}
+
+	return &g, nil
+}
+
+func loadFunction(path string) (Function, error) {
+	var typ, _, ok = strings.Cut(filepath.Base(path), ".")
+	if !ok {
+		return nil, fmt.Errorf("gadgetconfig: bad function directory `%s`", path)
+	}
+
+	var fn = NewFunction(typ)
+
+	if loader, ok := fn.(FunctionLoader); ok {
+		if err := loader.GadgetFunctionLoad(path); err != nil {
+			return nil, fmt.Errorf("gadgetconfig: load function `%s`: %w", filepath.Base(path), err)
+		}
+	}
+
+	return fn, nil
+}
+
+// Instance name of a function directory, such as `usb0` for `ncm.usb0`
+func instanceName(path string) string {
+	var _, name, _ = strings.Cut(filepath.Base(path), ".")
+	return name
+}
+
+func readAttr(elem ...string) string {
+	buf, err := os.ReadFile(filepath.Join(elem...))
+	if err != nil {
+		return ""
+	}
+
+	// Fixed length fields, such as an os_desc compatible_id, are NUL padded
+	return strings.TrimRight(string(buf), "\n\x00")
+}
+
+func readBinaryAttr(elem ...string) []byte {
+	buf, _ := os.ReadFile(filepath.Join(elem...))
+	return buf
+}
+
+// Decimal or `0x` prefixed hex, 0 if missing
+func readIntAttr(elem ...string) int {
+	v, _ := strconv.ParseInt(strings.TrimSpace(readAttr(elem...)), 0, 64)
+	return int(v)
+}
+
+// Hex with or without a `0x` prefix, 0 if missing
+func readHexAttr(elem ...string) int {
+	v, _ := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(readAttr(elem...)), "0x"), 16, 64)
+	return int(v)
+}
+
+func readBoolAttr(elem ...string) bool {
+	switch strings.TrimSpace(readAttr(elem...)) {
+	case "1", "Y", "y":
+		return true
+	default:
+		return false
+	}
+}
+
+// Read every attribute that can be both read and written, skipping
+// directories and read-only attributes such as `ifname`
+func readWritableAttrs(path string) (map[string]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs = make(map[string]string)
+
+	for _, ent := range entries {
+		if !ent.Type().IsRegular() {
+			continue
+		}
+
+		info, err := ent.Info()
+		if err != nil || info.Mode().Perm()&0o600 != 0o600 {
+			continue
+		}
+
+		if data, err := os.ReadFile(filepath.Join(path, ent.Name())); err == nil {
+			attrs[ent.Name()] = strings.TrimRight(string(data), "\n")
+		}
+	}
+
+	return attrs, nil
+}
+
+func (fn *AcmFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.Console = readBoolAttr(path, "console")
+	return nil
+}
+
+func (fn *EemFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.DevAddr = readAttr(path, "dev_addr")
+	fn.HostAddr = readAttr(path, "host_addr")
+	fn.Qmult = readIntAttr(path, "qmult")
+	return nil
+}
+
+func (fn *NcmFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.DevAddr = readAttr(path, "dev_addr")
+	fn.HostAddr = readAttr(path, "host_addr")
+	fn.Qmult = readIntAttr(path, "qmult")
+	fn.OsDesc = osDescInterfacesLoad(path)
+	return nil
+}
+
+func (fn *EcmFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.DevAddr = readAttr(path, "dev_addr")
+	fn.HostAddr = readAttr(path, "host_addr")
+	fn.Qmult = readIntAttr(path, "qmult")
+	return nil
+}
+
+func (fn *EcmSubsetFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.DevAddr = readAttr(path, "dev_addr")
+	fn.HostAddr = readAttr(path, "host_addr")
+	fn.Qmult = readIntAttr(path, "qmult")
+	return nil
+}
+
+func (fn *RndisFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.DevAddr = readAttr(path, "dev_addr")
+	fn.HostAddr = readAttr(path, "host_addr")
+	fn.Qmult = readIntAttr(path, "qmult")
+	fn.Class = readHexAttr(path, "class")
+	fn.Subclass = readHexAttr(path, "subclass")
+	fn.Protocol = readHexAttr(path, "protocol")
+	fn.OsDesc = osDescInterfacesLoad(path)
+	return nil
+}
+
+func (fn *SerialFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.Console = readBoolAttr(path, "console")
+	return nil
+}
+
+func (fn *ObexFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	return nil
+}
+
+func (fn *Uac1Function) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.Capture = loadAudioStream(path, "c_")
+	fn.Playback = loadAudioStream(path, "p_")
+	fn.ReqNumber = readIntAttr(path, "req_number")
+	fn.FunctionName = readAttr(path, "function_name")
+	return nil
+}
+
+func (fn *Uac2Function) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.Capture = loadAudioStream(path, "c_")
+	fn.Playback = loadAudioStream(path, "p_")
+	fn.ReqNumber = readIntAttr(path, "req_number")
+	fn.FbMax = readIntAttr(path, "fb_max")
+	fn.FunctionName = readAttr(path, "function_name")
+	return nil
+}
+
+func loadAudioStream(path, prefix string) *AudioStream {
+	var mask = readIntAttr(path, prefix+"chmask")
+	if mask == 0 {
+		return nil
+	}
+
+	var s = AudioStream{
+		ChannelMask:  mask,
+		SampleSize:   readIntAttr(path, prefix+"ssize"),
+		HsBint:       readIntAttr(path, prefix+"hs_bint"),
+		TerminalType: readIntAttr(path, prefix+"terminal_type"),
+		Sync:         readAttr(path, prefix+"sync"),
+	}
+
+	for _, v := range strings.Split(readAttr(path, prefix+"srate"), ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			s.SampleRates = append(s.SampleRates, n)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(path, prefix+"mute_present")); err == nil {
+		var mute = readBoolAttr(path, prefix+"mute_present")
+		s.Mute = &mute
+	}
+
+	if readBoolAttr(path, prefix+"volume_present") {
+		s.Volume = &AudioVolume{
+			Min: readIntAttr(path, prefix+"volume_min"),
+			Max: readIntAttr(path, prefix+"volume_max"),
+			Res: readIntAttr(path, prefix+"volume_res"),
+		}
+	}
+
+	return &s
+}
+
+func (fn *UvcFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.StreamingMaxPacket = readIntAttr(path, "streaming_maxpacket")
+	fn.StreamingMaxBurst = readIntAttr(path, "streaming_maxburst")
+	fn.StreamingInterval = readIntAttr(path, "streaming_interval")
+	fn.FunctionName = readAttr(path, "function_name")
+	fn.Formats = loadUvcFormats(path)
+	return nil
+}
+
+func (fn *MidiFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.Id = readAttr(path, "id")
+	fn.InPorts = readIntAttr(path, "in_ports")
+	fn.OutPorts = readIntAttr(path, "out_ports")
+	fn.BufLen = readIntAttr(path, "buflen")
+	fn.QLen = readIntAttr(path, "qlen")
+
+	var index = readIntAttr(path, "index")
+	fn.Index = &index
+
+	return nil
+}
+
+func (fn *PrinterFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.PnpString = readAttr(path, "pnp_string")
+	fn.QLen = readIntAttr(path, "q_len")
+	return nil
+}
+
+func (fn *HidFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.Protocol = readIntAttr(path, "protocol")
+	fn.Subclass = readIntAttr(path, "subclass")
+	fn.ReportLength = readIntAttr(path, "report_length")
+	fn.Descriptor = readBinaryAttr(path, "report_desc")
+	return nil
+}
+
+func (fn *MassStorageFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+
+	if _, err := os.Stat(filepath.Join(path, "stall")); err == nil {
+		var stall = readBoolAttr(path, "stall")
+		fn.Stall = &stall
+	}
+
+	luns, err := filepath.Glob(filepath.Join(path, "lun.*"))
+	if err != nil {
+		return err
+	}
+
+	for _, lunPath := range luns {
+		var lun = MassStorageLun{Name: strings.TrimPrefix(filepath.Base(lunPath), "lun.")}
+		lun.lunLoad(lunPath)
+
+		fn.Luns = append(fn.Luns, lun)
+	}
+
+	return nil
+}
+
+func (lun *MassStorageLun) lunLoad(path string) {
+	lun.File = readAttr(path, "file")
+	lun.Removable = readBoolAttr(path, "removable")
+	lun.Cdrom = readBoolAttr(path, "cdrom")
+	lun.ReadOnly = readBoolAttr(path, "ro")
+	lun.NoFua = readBoolAttr(path, "nofua")
+	lun.InquiryString = readAttr(path, "inquiry_string")
+}
+
+func (fn *LoopbackFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.QLen = readIntAttr(path, "qlen")
+	fn.BulkBufLen = readIntAttr(path, "bulk_buflen")
+	return nil
+}
+
+func (fn *SourceSinkFunction) GadgetFunctionLoad(path string) error {
+	fn.Name = instanceName(path)
+	fn.IsocInterval = readIntAttr(path, "isoc_interval")
+	fn.IsocMaxPacket = readIntAttr(path, "isoc_maxpacket")
+	fn.IsocMult = readIntAttr(path, "isoc_mult")
+	fn.IsocMaxBurst = readIntAttr(path, "isoc_maxburst")
+	fn.BulkBufLen = readIntAttr(path, "bulk_buflen")
+	fn.BulkQLen = readIntAttr(path, "bulk_qlen")
+	fn.IsoQLen = readIntAttr(path, "iso_qlen")
+
+	if n := readIntAttr(path, "pattern"); n >= 0 && n < len(sourceSinkPatterns) {
+		fn.Pattern = sourceSinkPatterns[n]
+	}
+
+	return nil
+}
+
+func (fn *GenericFunction) GadgetFunctionLoad(path string) (err error) {
+	fn.Name = instanceName(path)
+	fn.Attrs, err = readWritableAttrs(path)
+	return
+}
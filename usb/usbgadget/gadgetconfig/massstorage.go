@@ -0,0 +1,154 @@
+package gadgetconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Media control for the LUNs of a bound mass storage function. The kernel
+// opens the backing file as soon as it is written, so these take effect at
+// once, and the host sees a media change as it would for a removable disk.
+
+const (
+	massStorageMaxLuns    = 16
+	massStorageInquiryLen = 28
+)
+
+// LUN attributes the kernel only changes with the media ejected, or, for a
+// new file, once the host allows its removal
+var lunMediaAttrs = map[string]bool{"ro": true, "cdrom": true, "file": true}
+
+// LUN directory of a mass storage attribute path, if it is one
+func lunOf(root, path string) string {
+	rel, err := filepath.Rel(filepath.Join(root, "functions"), path)
+	if err != nil {
+		return ""
+	}
+
+	var parts = strings.Split(rel, string(filepath.Separator))
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "mass_storage.") || !strings.HasPrefix(parts[1], "lun.") {
+		return ""
+	}
+
+	return filepath.Join(root, "functions", parts[0], parts[1])
+}
+
+func (fn *MassStorageFunction) lunPath(g *Gadget, lun string) string {
+	return filepath.Join(g.gadgetPath(), functionPath(fn), "lun."+lun)
+}
+
+func (fn *MassStorageFunction) writeLun(g *Gadget, lun, attr, value string) error {
+	var path = filepath.Join(fn.lunPath(g, lun), attr)
+
+	if err := os.WriteFile(path, []byte(value), 0664); err != nil {
+		return fmt.Errorf("gadgetconfig: %s lun %s: %w", fn.GadgetFunctionName(), lun, err)
+	}
+
+	return nil
+}
+
+// Backing file of a LUN, empty when there is no media
+func (fn *MassStorageFunction) File(g *Gadget, lun string) (string, error) {
+	var path = fn.lunPath(g, lun)
+
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+
+	return readAttr(path, "file"), nil
+}
+
+// Remove the media, even if the host has locked it in place
+func (fn *MassStorageFunction) Eject(g *Gadget, lun string) error {
+	return fn.writeLun(g, lun, "forced_eject", "1")
+}
+
+// Eject the media and insert `file`, or leave the LUN empty when `file` is
+// empty
+func (fn *MassStorageFunction) SetFile(g *Gadget, lun, file string) error {
+	if err := fn.Eject(g, lun); err != nil {
+		return err
+	}
+
+	if file == "" {
+		return nil
+	}
+
+	return fn.writeLun(g, lun, "file", file)
+}
+
+// The kernel refuses to change `ro` with media present, so the media is
+// ejected and inserted again around the change. Should either fail, the
+// media is put back as it was.
+func (fn *MassStorageFunction) SetReadOnly(g *Gadget, lun string, ro bool) error {
+	file, err := fn.File(g, lun)
+	if err != nil {
+		return err
+	}
+
+	var wasRo = readAttr(fn.lunPath(g, lun), "ro")
+
+	if file != "" {
+		if err := fn.Eject(g, lun); err != nil {
+			return err
+		}
+	}
+
+	var reinsert = func() error {
+		if file == "" {
+			return nil
+		}
+		return fn.writeLun(g, lun, "file", file)
+	}
+
+	if err := fn.writeLun(g, lun, "ro", boolToIntStr(ro)); err != nil {
+		return errors.Join(err, reinsert())
+	}
+
+	if err := reinsert(); err != nil {
+		// The file may only open in its previous mode, such as one the
+		// gadget cannot write
+		if wasRo != "" {
+			err = errors.Join(err, fn.writeLun(g, lun, "ro", wasRo), reinsert())
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (fn *MassStorageFunction) Validate() error {
+	var (
+		errs  []error
+		names = make(map[string]bool)
+	)
+
+	if len(fn.Luns) > massStorageMaxLuns {
+		errs = append(errs, fmt.Errorf("more than %d LUNs", massStorageMaxLuns))
+	}
+
+	for _, lun := range fn.Luns {
+		if n, err := strconv.Atoi(lun.Name); err != nil || n < 0 || n >= massStorageMaxLuns {
+			errs = append(errs, fmt.Errorf("LUN name `%s` must be a number below %d", lun.Name, massStorageMaxLuns))
+		}
+
+		if names[lun.Name] {
+			errs = append(errs, fmt.Errorf("duplicate LUN `%s`", lun.Name))
+		}
+		names[lun.Name] = true
+
+		if len(lun.InquiryString) > massStorageInquiryLen {
+			errs = append(errs, fmt.Errorf("LUN `%s` inquiry string longer than %d", lun.Name, massStorageInquiryLen))
+		}
+
+		if lun.File == "" && !lun.Removable {
+			errs = append(errs, fmt.Errorf("LUN `%s` has no file and is not removable", lun.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
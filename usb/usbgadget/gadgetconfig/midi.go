@@ -0,0 +1,158 @@
+package gadgetconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The MIDI function registers an ALSA sound card when the gadget is bound,
+// whose rawmidi device carries the MIDI ports.
+
+const (
+	AsoundCardsPath = "/proc/asound/cards"
+
+	// Short name the kernel gives MIDI function cards
+	midiCardShortName = "f_midi"
+
+	midiMaxPorts = 16
+	alsaIdMaxLen = 15
+)
+
+var ErrNoMidiCard = errors.New("gadgetconfig: no MIDI function sound card")
+
+// MidiFunction
+type MidiFunction struct {
+	Name string `json:"name"`
+
+	// ALSA card index and ID, chosen by ALSA when unset
+	Index *int   `json:"index,omitempty"`
+	Id    string `json:"id,omitempty"`
+
+	InPorts  int `json:"in_ports,omitempty"`
+	OutPorts int `json:"out_ports,omitempty"`
+
+	// Request buffer length and queue length, left at the kernel's defaults
+	// when zero
+	BufLen int `json:"buflen,omitempty"`
+	QLen   int `json:"qlen,omitempty"`
+}
+
+var _ Function = (*MidiFunction)(nil)
+
+func (fn *MidiFunction) GadgetFunctionName() string { return "midi." + fn.Name }
+
+func (fn *MidiFunction) GadgetFunctionCreate() Steps {
+	var index string
+	if fn.Index != nil {
+		index = strconv.Itoa(*fn.Index)
+	}
+
+	return Steps{
+		Step{Write, "index", index},
+		Step{Write, "id", fn.Id},
+		Step{Write, "in_ports", intAttr(fn.InPorts)},
+		Step{Write, "out_ports", intAttr(fn.OutPorts)},
+		Step{Write, "buflen", intAttr(fn.BufLen)},
+		Step{Write, "qlen", intAttr(fn.QLen)},
+	}
+}
+
+func (fn *MidiFunction) Validate() error {
+	var errs []error
+
+	if fn.InPorts < 0 || fn.InPorts > midiMaxPorts || fn.OutPorts < 0 || fn.OutPorts > midiMaxPorts {
+		errs = append(errs, fmt.Errorf("ports must be 0 to %d", midiMaxPorts))
+	}
+
+	if fn.Index != nil && *fn.Index < -1 {
+		errs = append(errs, fmt.Errorf("card index %d out of range", *fn.Index))
+	}
+
+	if len(fn.Id) > alsaIdMaxLen {
+		errs = append(errs, fmt.Errorf("card ID `%s` longer than %d", fn.Id, alsaIdMaxLen))
+	}
+
+	if fn.BufLen < 0 || fn.QLen < 0 {
+		errs = append(errs, fmt.Errorf("buflen %d or qlen %d out of range", fn.BufLen, fn.QLen))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ALSA card number of the function, once the gadget is bound. Without a
+// fixed index or ID, this is the first MIDI function card, which is only
+// certain with a single MIDI function across all gadgets.
+func (fn *MidiFunction) Card(g *Gadget) (int, error) {
+	var path = filepath.Join(g.gadgetPath(), functionPath(fn))
+
+	if _, err := os.Stat(path); err != nil {
+		return -1, err
+	}
+
+	if index := readIntAttr(path, "index"); index >= 0 {
+		return index, nil
+	}
+
+	cards, err := readAsoundCards()
+	if err != nil {
+		return -1, err
+	}
+
+	var id = readAttr(path, "id")
+
+	for _, c := range cards {
+		if (id != "" && c.id == id) || (id == "" && c.shortName == midiCardShortName) {
+			return c.num, nil
+		}
+	}
+
+	return -1, ErrNoMidiCard
+}
+
+// Path of the function's rawmidi device, such as `/dev/snd/midiC1D0`
+func (fn *MidiFunction) RawMidiPath(g *Gadget) (string, error) {
+	card, err := fn.Card(g)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("/dev/snd/midiC%dD0", card), nil
+}
+
+// asoundCard
+type asoundCard struct {
+	num       int
+	id        string
+	shortName string
+}
+
+// Lines such as ` 1 [fmidi          ]: MIDI Gadget - f_midi`
+var asoundCardRe = regexp.MustCompile(`^\s*(\d+)\s+\[(\S+)\s*\]:\s+.* - (.*)$`)
+
+func readAsoundCards() (cards []asoundCard, err error) {
+	f, err := os.Open(AsoundCardsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var scanner = bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		m := asoundCardRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		num, _ := strconv.Atoi(m[1])
+		cards = append(cards, asoundCard{num: num, id: m[2], shortName: strings.TrimSpace(m[3])})
+	}
+
+	return cards, scanner.Err()
+}
@@ -0,0 +1,298 @@
+package gadgetconfig
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Microsoft OS descriptors, which Windows asks for to choose drivers such as
+// RNDIS or WinUSB without an INF file. The gadget answers the OS string
+// descriptor request with `qw_sign` and `b_vendor_code`, and the functions
+// in the config linked into `os_desc` give a compatible ID and extended
+// properties for each of their interfaces.
+//
+// See <https://docs.kernel.org/usb/gadget_configfs.html>
+
+// Defaults for zero fields, as configfs leaves both zero and Windows then
+// ignores the descriptors
+const (
+	OsDescQwSign     = "MSFT100"
+	OsDescVendorCode = 0xcd
+)
+
+// Maximum lengths of the OS descriptor fields
+const (
+	osDescQwSignLen = 7
+	osDescIdLen     = 8
+)
+
+// OsDesc
+type OsDesc struct {
+	// Vendor request code the host uses to fetch the descriptors, and the
+	// signature in the OS string descriptor, OsDescVendorCode and
+	// OsDescQwSign when zero
+	VendorCode int    `json:"vendor_code,omitempty"`
+	QwSign     string `json:"qw_sign,omitempty"`
+
+	// Config whose functions the descriptors describe, the first if empty
+	Config string `json:"config,omitempty"`
+}
+
+func (g *Gadget) osDescConfig() string {
+	if v := g.OsDesc.Config; v != "" {
+		return v
+	} else if len(g.Configs) > 0 {
+		return g.Configs[0].Name
+	}
+	return ""
+}
+
+// Enable the descriptors, relative to the gadget directory
+func (g *Gadget) osDescSteps() Steps {
+	if g.OsDesc == nil {
+		return nil
+	}
+
+	var vendorCode = g.OsDesc.VendorCode
+	if vendorCode == 0 {
+		vendorCode = OsDescVendorCode
+	}
+
+	return Steps{
+		Step{Write, "os_desc/use", "1"},
+		Step{Write, "os_desc/b_vendor_code", hexAttr(vendorCode, 2)},
+		Step{Write, "os_desc/qw_sign", orDefault(g.OsDesc.QwSign, OsDescQwSign)},
+	}
+}
+
+// Link the chosen config, once it exists
+func (g *Gadget) osDescLinkSteps() Steps {
+	if g.OsDesc == nil {
+		return nil
+	}
+
+	var name = g.osDescConfig()
+	return Steps{Step{Symlink, "configs/" + name, "os_desc/" + name}}
+}
+
+func (g *Gadget) osDescLoad(path string) {
+	if !readBoolAttr(path, "os_desc", "use") {
+		return
+	}
+
+	g.OsDesc = &OsDesc{
+		VendorCode: readIntAttr(path, "os_desc", "b_vendor_code"),
+		QwSign:     readAttr(path, "os_desc", "qw_sign"),
+	}
+
+	if links, _ := listLinks(filepath.Join(path, "os_desc")); len(links) > 0 {
+		g.OsDesc.Config = filepath.Base(links[0])
+	}
+}
+
+func (d *OsDesc) validate(g *Gadget) error {
+	if d.VendorCode < 0 || d.VendorCode > 0xff {
+		return fmt.Errorf("os_desc vendor code 0x%x out of range", d.VendorCode)
+	}
+
+	if len(d.QwSign) > osDescQwSignLen {
+		return fmt.Errorf("os_desc qw_sign `%s` longer than %d", d.QwSign, osDescQwSignLen)
+	}
+
+	var name = g.osDescConfig()
+	for i := range g.Configs {
+		if g.Configs[i].Name == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("os_desc config `%s` does not exist", name)
+}
+
+// Extended property types
+const (
+	OsDescPropSz         = 1
+	OsDescPropExpandSz   = 2
+	OsDescPropBinary     = 3
+	OsDescPropDwordLE    = 4
+	OsDescPropDwordBE    = 5
+	OsDescPropLink       = 6
+	OsDescPropMultiSz    = 7
+	osDescPropTypeLimit  = OsDescPropMultiSz
+	osDescPropNameMaxLen = 255
+)
+
+// OsDescInterface describes one of a function's interfaces, in its
+// `os_desc/interface.<Name>` directory, which the function provides
+type OsDescInterface struct {
+	Name            string           `json:"name"`
+	CompatibleId    string           `json:"compatible_id,omitempty"`
+	SubCompatibleId string           `json:"sub_compatible_id,omitempty"`
+	Properties      []OsDescProperty `json:"properties,omitempty"`
+}
+
+// OsDescProperty is a registry value for the interface's device. The Value
+// is text for string types, strings separated by newlines for MultiSz, hex
+// for Binary, and a number for the Dword types.
+type OsDescProperty struct {
+	Name  string `json:"name"`
+	Type  int    `json:"type"`
+	Value string `json:"value"`
+}
+
+func (p *OsDescProperty) data() ([]byte, error) {
+	switch p.Type {
+	case OsDescPropSz, OsDescPropExpandSz, OsDescPropLink:
+		return []byte(p.Value), nil
+
+	case OsDescPropMultiSz:
+		return []byte(strings.ReplaceAll(p.Value, "\n", "\x00") + "\x00"), nil
+
+	case OsDescPropBinary:
+		return hex.DecodeString(p.Value)
+
+	case OsDescPropDwordLE, OsDescPropDwordBE:
+		v, err := strconv.ParseUint(p.Value, 0, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Type == OsDescPropDwordLE {
+			return binary.LittleEndian.AppendUint32(nil, uint32(v)), nil
+		}
+		return binary.BigEndian.AppendUint32(nil, uint32(v)), nil
+
+	default:
+		return nil, fmt.Errorf("bad type %d", p.Type)
+	}
+}
+
+func (p *OsDescProperty) load(path string) {
+	p.Name = filepath.Base(path)
+	p.Type = readIntAttr(path, "type")
+
+	var data = readBinaryAttr(path, "data")
+
+	switch p.Type {
+	case OsDescPropMultiSz:
+		p.Value = strings.ReplaceAll(strings.TrimRight(string(data), "\x00"), "\x00", "\n")
+
+	case OsDescPropBinary:
+		p.Value = hex.EncodeToString(data)
+
+	case OsDescPropDwordLE:
+		if len(data) == 4 {
+			p.Value = strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10)
+		}
+
+	case OsDescPropDwordBE:
+		if len(data) == 4 {
+			p.Value = strconv.FormatUint(uint64(binary.BigEndian.Uint32(data)), 10)
+		}
+
+	default:
+		p.Value = string(data)
+	}
+}
+
+// Steps for each interface, relative to the function directory. Property
+// types must be written before their data, which the kernel interprets
+// according to the type. The kernel drops a final newline or NUL from
+// property data, so binary data is written with a newline to protect its
+// last byte.
+func osDescInterfaceSteps(ifaces []OsDescInterface) (steps Steps) {
+	for _, iface := range ifaces {
+		var prefix = "os_desc/interface." + iface.Name
+
+		steps.Append(Step{Write, prefix + "/compatible_id", iface.CompatibleId})
+		steps.Append(Step{Write, prefix + "/sub_compatible_id", iface.SubCompatibleId})
+
+		for i := range iface.Properties {
+			var (
+				p       = &iface.Properties[i]
+				dir     = prefix + "/" + p.Name
+				data, _ = p.data()
+			)
+
+			steps.Append(Step{Mkdir, dir, ""})
+			steps.Append(Step{Write, dir + "/type", strconv.Itoa(p.Type)})
+
+			switch p.Type {
+			case OsDescPropSz, OsDescPropExpandSz, OsDescPropLink:
+				steps.Append(Step{Write, dir + "/data", string(data)})
+			default:
+				steps.Append(Step{WriteBinary, dir + "/data", string(data) + "\n"})
+			}
+		}
+	}
+
+	return
+}
+
+func osDescInterfacesLoad(path string) (ifaces []OsDescInterface) {
+	var dirs, _ = listDirs(filepath.Join(path, "os_desc"))
+
+	for _, dir := range dirs {
+		name, ok := strings.CutPrefix(dir, "interface.")
+		if !ok {
+			continue
+		}
+
+		var (
+			ifacePath = filepath.Join(path, "os_desc", dir)
+			iface     = OsDescInterface{
+				Name:            name,
+				CompatibleId:    readAttr(ifacePath, "compatible_id"),
+				SubCompatibleId: readAttr(ifacePath, "sub_compatible_id"),
+			}
+			props, _ = listDirs(ifacePath)
+		)
+
+		for _, prop := range props {
+			var p OsDescProperty
+			p.load(filepath.Join(ifacePath, prop))
+			iface.Properties = append(iface.Properties, p)
+		}
+
+		if iface.CompatibleId != "" || iface.SubCompatibleId != "" || len(iface.Properties) > 0 {
+			ifaces = append(ifaces, iface)
+		}
+	}
+
+	return
+}
+
+func validateOsDescInterfaces(ifaces []OsDescInterface) error {
+	var errs []error
+
+	for _, iface := range ifaces {
+		if iface.Name == "" || strings.ContainsRune(iface.Name, '/') {
+			errs = append(errs, fmt.Errorf("bad os_desc interface name `%s`", iface.Name))
+		}
+
+		if len(iface.CompatibleId) > osDescIdLen || len(iface.SubCompatibleId) > osDescIdLen {
+			errs = append(errs, fmt.Errorf("os_desc interface `%s` IDs longer than %d", iface.Name, osDescIdLen))
+		}
+
+		for i := range iface.Properties {
+			var p = &iface.Properties[i]
+
+			if p.Name == "" || len(p.Name) > osDescPropNameMaxLen || strings.ContainsRune(p.Name, '/') {
+				errs = append(errs, fmt.Errorf("bad os_desc property name `%s`", p.Name))
+			}
+
+			if p.Type < OsDescPropSz || p.Type > osDescPropTypeLimit {
+				errs = append(errs, fmt.Errorf("os_desc property `%s` type %d out of range", p.Name, p.Type))
+			} else if _, err := p.data(); err != nil {
+				errs = append(errs, fmt.Errorf("os_desc property `%s`: %w", p.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
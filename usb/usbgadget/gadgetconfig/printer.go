@@ -0,0 +1,36 @@
+package gadgetconfig
+
+import "fmt"
+
+// The printer function's device node, `/dev/g_printer<N>`, is served by the
+// printergadget package.
+
+// PrinterFunction
+type PrinterFunction struct {
+	Name string `json:"name"`
+
+	// IEEE 1284 device ID the host reads, such as
+	// `MFG:Example;MDL:Printer;CMD:PCL;CLS:PRINTER;`
+	PnpString string `json:"pnp_string,omitempty"`
+
+	// Requests queued each way, left at the kernel's default when zero
+	QLen int `json:"q_len,omitempty"`
+}
+
+var _ Function = (*PrinterFunction)(nil)
+
+func (fn *PrinterFunction) GadgetFunctionName() string { return "printer." + fn.Name }
+
+func (fn *PrinterFunction) GadgetFunctionCreate() Steps {
+	return Steps{
+		Step{Write, "pnp_string", fn.PnpString},
+		Step{Write, "q_len", intAttr(fn.QLen)},
+	}
+}
+
+func (fn *PrinterFunction) Validate() error {
+	if fn.QLen < 0 {
+		return fmt.Errorf("q_len %d out of range", fn.QLen)
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package gadgetconfig
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reconciling a live gadget with a desired definition. The plan keeps
+// whatever already matches, so the host connection and network interfaces
+// survive changes that the kernel allows in place.
+//
+// Function attributes cannot be changed while the function is linked into a
+// config, so changing them means unlinking, writing and relinking. Unlinking
+// unbinds the gadget, as does any change to which functions are linked, so
+// those plans begin with an explicit Unbind and end by binding the UDC again.
+// A definition without a UDC keeps the gadget on the one it is bound to.
+// Device and config attributes and strings are written in place, and take
+// effect when the host next enumerates the device. A string left empty in
+// the definition is cleared on the live gadget. Mass storage LUNs also
+// change in place, with their media ejected and inserted again around
+// changes the kernel refuses while it is present, as SetReadOnly does.
+
+func (g *Gadget) Reconcile() error {
+	steps, err := g.ReconcileSteps()
+	if err != nil {
+		return err
+	}
+
+	return steps.RunTransaction()
+}
+
+func (g *Gadget) ShellReconcile() (ShellSteps, error) {
+	steps, err := g.ReconcileSteps()
+	if err != nil {
+		return nil, err
+	}
+
+	return steps.ShellArgs(), nil
+}
+
+// Plan the changes that bring the live gadget in line with this definition,
+// which are all of CreateSteps when it does not exist yet
+func (g *Gadget) ReconcileSteps() (Steps, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !g.Exists() {
+		return g.CreateSteps(), nil
+	}
+
+	var (
+		root    = g.gadgetPath()
+		desired = g.CreateSteps()
+
+		// Functions with attributes to change, and so to be unlinked first
+		dirty = make(map[string]bool)
+
+		wantFunctions = make(map[string]bool)
+		wantConfigs   = make(map[string]bool)
+		wantLinks     = make(map[string]bool)
+	)
+
+	if g.OsDesc != nil {
+		wantLinks[filepath.Join(root, "os_desc", g.osDescConfig())] = true
+	}
+
+	for i := range g.Configs {
+		var c = &g.Configs[i]
+		wantConfigs[c.Name] = true
+
+		for _, fn := range c.Functions {
+			wantFunctions[fn.GadgetFunctionName()] = true
+			wantLinks[filepath.Join(root, c.configPath(), fn.GadgetFunctionName())] = true
+		}
+	}
+
+	// LUN attributes change in place, through an eject where needed
+	for _, s := range desired {
+		if (s.Action == Write || s.Action == WriteBinary) && !attrMatches(s) && lunOf(root, s.Arg0) == "" {
+			if fn := functionOf(root, s.Arg0); fn != "" {
+				dirty[fn] = true
+			}
+		}
+	}
+
+	var (
+		unlink, remove, change Steps
+		relink                 = false
+	)
+
+	// Links to remove, either unwanted or to a function about to change
+	liveConfigs, err := listDirs(filepath.Join(root, "configs"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range liveConfigs {
+		var configPath = filepath.Join(root, "configs", config)
+
+		links, err := listLinks(configPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			target, _ := os.Readlink(link)
+
+			if !wantLinks[link] || dirty[filepath.Base(target)] {
+				unlink.Append(Step{Remove, link, ""})
+			}
+		}
+
+		if !wantConfigs[config] {
+			remove.Extend(configTeardown(configPath))
+		}
+	}
+
+	remove.Extend(g.staleStrings(root))
+
+	// Only one config may be linked into os_desc, so any other goes first
+	osDescLinks, _ := listLinks(filepath.Join(root, "os_desc"))
+	for _, link := range osDescLinks {
+		if !wantLinks[link] {
+			unlink.Append(Step{Remove, link, ""})
+		}
+	}
+
+	liveFunctions, err := listDirs(filepath.Join(root, "functions"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fn := range liveFunctions {
+		if !wantFunctions[fn] {
+			remove.Extend(functionTeardown(filepath.Join(root, "functions", fn)))
+		}
+	}
+
+	// Everything desired that is missing or different, keeping comments
+	// only for sections with something to do
+	var (
+		comment  *Step
+		ejected  = make(map[string]bool)
+		udcPath  = filepath.Join(root, "UDC")
+		keepStep = func(s Step) {
+			if comment != nil {
+				change.Append(*comment)
+				comment = nil
+			}
+			change.Append(s)
+		}
+	)
+
+	for i, s := range desired {
+		switch s.Action {
+		case Comment:
+			comment = &desired[i]
+
+		case Mkdir, MkdirCreateOnly:
+			if fi, err := os.Stat(s.Arg0); err != nil || !fi.IsDir() {
+				keepStep(s)
+			}
+
+		case Bind:
+			// Bound last, below
+
+		case Write, WriteBinary:
+			var (
+				lun  = lunOf(root, s.Arg0)
+				attr = filepath.Base(s.Arg0)
+			)
+
+			switch {
+			case !attrMatches(s):
+				if lun != "" && lunMediaAttrs[attr] && !ejected[lun] && readAttr(lun, "file") != "" {
+					keepStep(Step{Write, filepath.Join(lun, "forced_eject"), "1"})
+					ejected[lun] = true
+				}
+				keepStep(s)
+
+			case ejected[lun] && attr == "file":
+				// Insert the media again, after the changes it was ejected for
+				keepStep(s)
+
+			case s.Arg1 == "" && stringAttr(root, s.Arg0) && readAttr(s.Arg0) != "":
+				keepStep(Step{Write, s.Arg0, clearString})
+			}
+
+		case Symlink:
+			target, err := os.Readlink(s.Arg1)
+			if err != nil || filepath.Base(target) != filepath.Base(s.Arg0) || dirty[filepath.Base(s.Arg0)] {
+				keepStep(s)
+				relink = true
+			}
+
+		default:
+			keepStep(s)
+		}
+	}
+
+	if g.OsDesc == nil && readBoolAttr(root, "os_desc", "use") {
+		change.Append(Step{Write, filepath.Join(root, "os_desc", "use"), "0"})
+	}
+
+	var (
+		steps      Steps
+		currentUdc = readAttr(udcPath)
+		wantUdc    = orDefault(g.UDC, currentUdc)
+	)
+
+	if len(unlink) > 0 || len(remove) > 0 || relink {
+		if currentUdc != "" {
+			steps.Append(Step{Unbind, udcPath, ""})
+			currentUdc = ""
+		}
+	}
+
+	steps.Extend(unlink)
+	steps.Extend(remove)
+	steps.Extend(change)
+
+	if wantUdc != currentUdc {
+		if currentUdc != "" {
+			steps.Append(Step{Unbind, udcPath, ""})
+		}
+
+		if wantUdc != "" {
+			steps.Append(Step{Bind, udcPath, wantUdc})
+		}
+	}
+
+	return steps, nil
+}
+
+// Whether a write step's value is already in place. Unset values are
+// never written, so always match.
+func attrMatches(s Step) bool {
+	if s.Arg1 == "" {
+		return true
+	}
+
+	data, err := os.ReadFile(s.Arg0)
+	if err != nil {
+		return false
+	}
+
+	// Some attributes drop a newline written after binary data
+	if s.Action == WriteBinary {
+		return bytes.Equal(data, []byte(s.Arg1)) || bytes.Equal(data, []byte(strings.TrimSuffix(s.Arg1, "\n")))
+	}
+
+	// Fixed length fields are read back NUL padded, and some hex values
+	// without their `0x`
+	var (
+		have = strings.TrimRight(string(data), "\n\x00")
+		want = strings.TrimRight(s.Arg1, "\n")
+	)
+
+	if hex, ok := strings.CutPrefix(want, "0x"); ok && strings.EqualFold(have, hex) {
+		return true
+	}
+
+	return have == want
+}
+
+// Name of the function directory containing `path`, if any
+func functionOf(root, path string) string {
+	rel, err := filepath.Rel(filepath.Join(root, "functions"), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	fn, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return fn
+}
+
+func listDirs(path string) (names []string, err error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ent := range entries {
+		if ent.IsDir() {
+			names = append(names, ent.Name())
+		}
+	}
+
+	return
+}
+
+func listLinks(path string) (links []string, err error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ent := range entries {
+		if ent.Type()&os.ModeSymlink != 0 {
+			links = append(links, filepath.Join(path, ent.Name()))
+		}
+	}
+
+	return
+}
+
+// Languages and extra strings that are no longer wanted, in the gadget and
+// in the configs that stay
+func (g *Gadget) staleStrings(root string) (steps Steps) {
+	var (
+		langs       = g.langStrings()
+		stringsPath = filepath.Join(root, "strings")
+	)
+
+	for _, id := range listLangs(stringsPath) {
+		var dir = filepath.Join(stringsPath, id.String())
+
+		want, ok := langs[id]
+		if !ok {
+			steps.Extend(langTeardown(dir))
+			continue
+		}
+
+		extras, _ := listDirs(dir)
+		for _, name := range extras {
+			if _, ok := want.Extra[name]; !ok {
+				steps.Append(Step{Rmdir, filepath.Join(dir, name), ""})
+			}
+		}
+	}
+
+	for i := range g.Configs {
+		var (
+			c     = &g.Configs[i]
+			langs = c.langStrings()
+			path  = filepath.Join(root, c.configPath(), "strings")
+		)
+
+		for _, id := range listLangs(path) {
+			if _, ok := langs[id]; !ok {
+				steps.Extend(langTeardown(filepath.Join(path, id.String())))
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,154 @@
+package gadgetconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// The serial functions each get a port of the gadget serial driver, which
+// the kernel numbers as it creates them. The number is only known once the
+// function directory exists, from its read-only `port_num` attribute.
+
+// Prefix of the gadget serial tty devices
+const TtyGSPrefix = "/dev/ttyGS"
+
+// SerialPort is implemented by functions with a gadget serial tty
+type SerialPort interface {
+	Function
+	PortNum(g *Gadget) (int, error)
+}
+
+var (
+	_ SerialPort = (*AcmFunction)(nil)
+	_ SerialPort = (*SerialFunction)(nil)
+	_ SerialPort = (*ObexFunction)(nil)
+)
+
+func readPortNum(g *Gadget, fn Function) (int, error) {
+	data, err := os.ReadFile(filepath.Join(g.gadgetPath(), functionPath(fn), "port_num"))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("gadgetconfig: %s bad port_num: %w", fn.GadgetFunctionName(), err)
+	}
+
+	return n, nil
+}
+
+// Path of the tty for a serial function of a created gadget, such as
+// `/dev/ttyGS0`
+func TtyPath(g *Gadget, port SerialPort) (string, error) {
+	n, err := port.PortNum(g)
+	if err != nil {
+		return "", err
+	}
+
+	return TtyGSPrefix + strconv.Itoa(n), nil
+}
+
+// Open the tty for a serial function, in raw mode
+func OpenTty(g *Gadget, port SerialPort) (*os.File, error) {
+	path, err := TtyPath(g, port)
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenRawTty(path)
+}
+
+// Open a tty with no line discipline processing, echo or signals, 8 bit
+// characters, and reads that return as soon as any data arrives
+func OpenRawTty(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := makeRaw(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("gadgetconfig: %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// As cfmakeraw(3), through SyscallConn so the descriptor stays non-blocking
+func makeRaw(f *os.File) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var ioErr error
+	if err := rc.Control(func(fd uintptr) {
+		var t *unix.Termios
+		if t, ioErr = unix.IoctlGetTermios(int(fd), unix.TCGETS); ioErr != nil {
+			return
+		}
+
+		t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+		t.Oflag &^= unix.OPOST
+		t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		t.Cflag &^= unix.CSIZE | unix.PARENB
+		t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL
+		t.Cc[unix.VMIN] = 1
+		t.Cc[unix.VTIME] = 0
+
+		ioErr = unix.IoctlSetTermios(int(fd), unix.TCSETS, t)
+	}); err != nil {
+		return err
+	}
+
+	return ioErr
+}
+
+func (fn *AcmFunction) PortNum(g *Gadget) (int, error) { return readPortNum(g, fn) }
+
+// SerialFunction is the generic serial function, `gser`, without the CDC
+// ACM control interface
+type SerialFunction struct {
+	Name    string `json:"name"`
+	Console bool   `json:"console,omitempty"`
+}
+
+var _ Function = (*SerialFunction)(nil)
+
+func (fn *SerialFunction) GadgetFunctionName() string { return "gser." + fn.Name }
+
+func (fn *SerialFunction) GadgetFunctionCreate() Steps {
+	return Steps{
+		Step{Write, "console", consoleAttr(fn.Console)},
+	}
+}
+
+func (fn *SerialFunction) PortNum(g *Gadget) (int, error) { return readPortNum(g, fn) }
+
+// ObexFunction
+type ObexFunction struct {
+	Name string `json:"name"`
+}
+
+var _ Function = (*ObexFunction)(nil)
+
+func (fn *ObexFunction) GadgetFunctionName() string { return "obex." + fn.Name }
+
+func (fn *ObexFunction) GadgetFunctionCreate() Steps { return nil }
+
+func (fn *ObexFunction) PortNum(g *Gadget) (int, error) { return readPortNum(g, fn) }
+
+// The `console` attribute only exists when the kernel has gadget serial
+// console support, so it is only written to enable it
+func consoleAttr(console bool) string {
+	if console {
+		return "1"
+	}
+	return ""
+}
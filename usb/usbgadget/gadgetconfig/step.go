@@ -2,8 +2,10 @@ package gadgetconfig
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -24,6 +26,9 @@ const (
 	WriteBinary
 	Remove
 	Symlink
+	Unbind
+	RmdirOptional
+	Bind
 )
 
 // Step
@@ -41,7 +46,7 @@ func (s Step) Run() error {
 	case Rmdir:
 		return os.Remove(s.Arg0)
 
-	case Write, WriteBinary:
+	case Write, WriteBinary, Bind:
 		if s.Arg1 != "" {
 			return ioutil.WriteFile(s.Arg0, []byte(s.Arg1), 0664)
 		} else {
@@ -54,6 +59,16 @@ func (s Step) Run() error {
 	case Symlink:
 		return os.Symlink(s.Arg0, s.Arg1)
 
+	case Unbind:
+		return ioutil.WriteFile(s.Arg0, []byte("\n"), 0664)
+
+	case RmdirOptional:
+		// Default groups cannot be removed, and go away with their parent
+		if err := os.Remove(s.Arg0); err != nil && !errors.Is(err, fs.ErrPermission) && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+
 	default:
 		return nil
 	}
@@ -89,12 +104,19 @@ func (s Step) ShellArgs() []string {
 	case Rmdir:
 		return []string{"rmdir", s.Arg0}
 
-	case Write:
-		return []string{"echo", fmt.Sprintf(`"%s"`, s.Arg1), "|", "tee", s.Arg0}
+	case Write, Bind, WriteBinary:
+		// Empty values are not written, as in Run
+		if s.Arg1 == "" {
+			return nil
+		}
 
-	case WriteBinary:
-		var encoded = base64.StdEncoding.EncodeToString([]byte(s.Arg1))
-		return []string{"echo", fmt.Sprintf(`"%s"`, encoded), "|", "base64", "-d", "|", "tee", s.Arg0}
+		if s.Action == WriteBinary {
+			var encoded = base64.StdEncoding.EncodeToString([]byte(s.Arg1))
+			return []string{"echo", fmt.Sprintf(`"%s"`, encoded), "|", "base64", "-d", "|", "tee", s.Arg0}
+		}
+
+		// echo adds the newline, as for a string cleared with one alone
+		return []string{"echo", fmt.Sprintf(`"%s"`, strings.TrimSuffix(s.Arg1, "\n")), "|", "tee", s.Arg0}
 
 	case Remove:
 		return []string{"rm", "-f", s.Arg0}
@@ -102,11 +124,19 @@ func (s Step) ShellArgs() []string {
 	case Symlink:
 		return []string{"ln", "-s", s.Arg0, s.Arg1}
 
+	case Unbind:
+		return []string{"echo", `""`, "|", "tee", s.Arg0}
+
+	case RmdirOptional:
+		return []string{"rmdir", s.Arg0, "2>/dev/null", "||", "true"}
+
 	default:
 		return nil
 	}
 }
 
+// Step reversing this one, where that needs nothing but the step itself.
+// Removals are not reversible here; RunTransaction restores removed links.
 func (s Step) Undo() Step {
 	switch s.Action {
 	case Mkdir:
@@ -115,6 +145,9 @@ func (s Step) Undo() Step {
 	case Symlink:
 		return Step{Remove, s.Arg1, ""}
 
+	case Bind:
+		return Step{Unbind, s.Arg0, ""}
+
 	default:
 		return Step{Noop, "", ""}
 	}
@@ -0,0 +1,341 @@
+package gadgetconfig
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// String descriptors, in one `strings/<langid>` directory per language. The
+// plain string fields of Gadget and Config are the English strings, which
+// also fill in any string a translation leaves out. Function interface
+// strings are not translated, as the kernel's functions take them from
+// single-language attributes of their own.
+
+// LangId is a USB language ID, as in the host's string descriptor zero
+type LangId uint16
+
+const (
+	LangEnglish  LangId = 0x0409
+	LangGerman   LangId = 0x0407
+	LangFrench   LangId = 0x040c
+	LangJapanese LangId = 0x0411
+	LangChinese  LangId = 0x0804
+)
+
+// Longest string descriptor, in UTF-16 code units
+const MaxStringLen = 126
+
+// Written to clear a string, as the kernel drops a trailing newline and
+// empty values are never written
+const clearString = "\n"
+
+// Directory name, such as `0x409`
+func (id LangId) String() string { return fmt.Sprintf("0x%x", uint16(id)) }
+
+// Whether the ID has a primary language and sub-language, as the kernel
+// requires
+func (id LangId) Valid() bool {
+	var (
+		primary = id & 0x3ff
+		sub     = id >> 10
+	)
+
+	switch {
+	case primary == 0, primary >= 0x62 && primary <= 0xfe, primary >= 0x100:
+		return false
+	}
+
+	return sub != 0
+}
+
+func ParseLangId(s string) (LangId, error) {
+	v, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("gadgetconfig: bad language ID `%s`: %w", s, err)
+	}
+
+	return LangId(v), nil
+}
+
+func (id LangId) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+
+func (id *LangId) UnmarshalText(text []byte) (err error) {
+	*id, err = ParseLangId(string(text))
+	return
+}
+
+// GadgetStrings
+type GadgetStrings struct {
+	SerialNumber string `json:"serial_number,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+
+	// Further strings by name, in `strings/<langid>/<name>/s`, which no
+	// kernel function can refer to yet
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// ConfigStrings
+type ConfigStrings struct {
+	Configuration string `json:"configuration,omitempty"`
+}
+
+// English first, then by ID
+func sortedLangs[T any](m map[LangId]T) []LangId {
+	var langs = make([]LangId, 0, len(m))
+	for id := range m {
+		langs = append(langs, id)
+	}
+
+	slices.SortFunc(langs, func(a, b LangId) int {
+		switch {
+		case a == b:
+			return 0
+		case a == LangEnglish:
+			return -1
+		case b == LangEnglish:
+			return 1
+		default:
+			return int(a) - int(b)
+		}
+	})
+
+	return langs
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+func orDefault(s, def string) string {
+	if s != "" {
+		return s
+	}
+	return def
+}
+
+// Strings for each language, with the English fields merged in
+func (g *Gadget) langStrings() map[LangId]GadgetStrings {
+	var (
+		en    = g.Strings[LangEnglish]
+		langs = map[LangId]GadgetStrings{
+			LangEnglish: {
+				SerialNumber: orDefault(g.SerialNumber, en.SerialNumber),
+				Manufacturer: orDefault(g.Manufacturer, en.Manufacturer),
+				Product:      orDefault(g.Product, en.Product),
+				Extra:        en.Extra,
+			},
+		}
+	)
+
+	en = langs[LangEnglish]
+
+	for id, s := range g.Strings {
+		if id == LangEnglish {
+			continue
+		}
+
+		var extra = make(map[string]string)
+		for name, v := range en.Extra {
+			extra[name] = orDefault(s.Extra[name], v)
+		}
+		for name, v := range s.Extra {
+			extra[name] = v
+		}
+
+		langs[id] = GadgetStrings{
+			SerialNumber: orDefault(s.SerialNumber, en.SerialNumber),
+			Manufacturer: orDefault(s.Manufacturer, en.Manufacturer),
+			Product:      orDefault(s.Product, en.Product),
+			Extra:        extra,
+		}
+	}
+
+	return langs
+}
+
+func (c *Config) langStrings() map[LangId]ConfigStrings {
+	var (
+		en    = orDefault(c.Configuration, c.Strings[LangEnglish].Configuration)
+		langs = map[LangId]ConfigStrings{
+			LangEnglish: {Configuration: en},
+		}
+	)
+
+	for id, s := range c.Strings {
+		if id != LangEnglish {
+			langs[id] = ConfigStrings{Configuration: orDefault(s.Configuration, en)}
+		}
+	}
+
+	return langs
+}
+
+// Relative to the gadget directory
+func (g *Gadget) stringsSteps() (steps Steps) {
+	var langs = g.langStrings()
+
+	for _, id := range sortedLangs(langs) {
+		var (
+			s   = langs[id]
+			dir = "strings/" + id.String()
+		)
+
+		steps.Extend(Steps{
+			Step{Mkdir, dir, ""},
+			Step{Write, dir + "/serialnumber", s.SerialNumber},
+			Step{Write, dir + "/manufacturer", s.Manufacturer},
+			Step{Write, dir + "/product", s.Product},
+		})
+
+		for _, name := range sortedKeys(s.Extra) {
+			steps.Append(Step{Mkdir, dir + "/" + name, ""})
+			steps.Append(Step{Write, dir + "/" + name + "/s", s.Extra[name]})
+		}
+	}
+
+	return
+}
+
+// Relative to the config directory
+func (c *Config) stringsSteps() (steps Steps) {
+	var langs = c.langStrings()
+
+	for _, id := range sortedLangs(langs) {
+		var dir = "strings/" + id.String()
+
+		steps.Append(Step{Mkdir, dir, ""})
+		steps.Append(Step{Write, dir + "/configuration", langs[id].Configuration})
+	}
+
+	return
+}
+
+// Whether `path` is a string of the gadget or of one of its configs
+func stringAttr(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	var parts = strings.Split(rel, string(filepath.Separator))
+	return parts[0] == "strings" || (len(parts) > 2 && parts[0] == "configs" && parts[2] == "strings")
+}
+
+// Language directories of a `strings` group, skipping any not named by ID
+func listLangs(path string) (langs []LangId) {
+	var dirs, _ = listDirs(path)
+
+	for _, dir := range dirs {
+		if id, err := ParseLangId(dir); err == nil {
+			langs = append(langs, id)
+		}
+	}
+
+	return
+}
+
+func (g *Gadget) stringsLoad(path string) {
+	for _, id := range listLangs(filepath.Join(path, "strings")) {
+		var (
+			dir = filepath.Join(path, "strings", id.String())
+			s   = GadgetStrings{
+				SerialNumber: readAttr(dir, "serialnumber"),
+				Manufacturer: readAttr(dir, "manufacturer"),
+				Product:      readAttr(dir, "product"),
+			}
+			extras, _ = listDirs(dir)
+		)
+
+		for _, name := range extras {
+			if s.Extra == nil {
+				s.Extra = make(map[string]string)
+			}
+			s.Extra[name] = readAttr(dir, name, "s")
+		}
+
+		if id == LangEnglish {
+			g.SerialNumber, g.Manufacturer, g.Product = s.SerialNumber, s.Manufacturer, s.Product
+			s = GadgetStrings{Extra: s.Extra}
+
+			if s.Extra == nil {
+				continue
+			}
+		}
+
+		if g.Strings == nil {
+			g.Strings = make(map[LangId]GadgetStrings)
+		}
+		g.Strings[id] = s
+	}
+}
+
+func (c *Config) stringsLoad(path string) {
+	for _, id := range listLangs(filepath.Join(path, "strings")) {
+		var v = readAttr(path, "strings", id.String(), "configuration")
+
+		if id == LangEnglish {
+			c.Configuration = v
+			continue
+		}
+
+		if c.Strings == nil {
+			c.Strings = make(map[LangId]ConfigStrings)
+		}
+		c.Strings[id] = ConfigStrings{Configuration: v}
+	}
+}
+
+func validateString(what, s string) error {
+	if n := len(utf16.Encode([]rune(s))); n > MaxStringLen {
+		return fmt.Errorf("%s string is %d UTF-16 units, longer than %d", what, n, MaxStringLen)
+	}
+	return nil
+}
+
+func (g *Gadget) validateStrings() error {
+	var errs []error
+
+	for id, s := range g.langStrings() {
+		if !id.Valid() {
+			errs = append(errs, fmt.Errorf("bad language ID %s", id))
+		}
+
+		errs = append(errs,
+			validateString(id.String()+" serial number", s.SerialNumber),
+			validateString(id.String()+" manufacturer", s.Manufacturer),
+			validateString(id.String()+" product", s.Product),
+		)
+
+		for name, v := range s.Extra {
+			if name == "" || strings.ContainsRune(name, '/') {
+				errs = append(errs, fmt.Errorf("bad string name `%s`", name))
+			}
+			errs = append(errs, validateString(id.String()+" `"+name+"`", v))
+		}
+	}
+
+	for i := range g.Configs {
+		var c = &g.Configs[i]
+
+		for id, s := range c.langStrings() {
+			if !id.Valid() {
+				errs = append(errs, fmt.Errorf("config `%s` bad language ID %s", c.Name, id))
+			}
+			errs = append(errs, validateString("config `"+c.Name+"` "+id.String(), s.Configuration))
+		}
+	}
+
+	return errors.Join(errs...)
+}
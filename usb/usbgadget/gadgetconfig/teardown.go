@@ -0,0 +1,128 @@
+package gadgetconfig
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Teardown works from what is actually in configfs rather than from a
+// definition, so it also removes functions, configs, strings and links that
+// were added by someone else. The order follows the kernel's gadget
+// configfs documentation: unbind, unlink, then remove configs, functions
+// and strings, and finally the gadget itself.
+
+// Plan the removal of the gadget at `path`, as it currently exists
+func TeardownSteps(path string) (Steps, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	var steps Steps
+
+	if readAttr(path, "UDC") != "" {
+		steps.Append(Step{Unbind, filepath.Join(path, "UDC"), ""})
+	}
+
+	configs, err := listDirs(filepath.Join(path, "configs"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Links from os_desc to a config, and from configs to functions
+	osDescLinks, _ := listLinks(filepath.Join(path, "os_desc"))
+	for _, link := range osDescLinks {
+		steps.Append(Step{Remove, link, ""})
+	}
+
+	for _, config := range configs {
+		links, err := listLinks(filepath.Join(path, "configs", config))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			steps.Append(Step{Remove, link, ""})
+		}
+	}
+
+	for _, config := range configs {
+		steps.Extend(configTeardown(filepath.Join(path, "configs", config)))
+	}
+
+	functions, err := listDirs(filepath.Join(path, "functions"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fn := range functions {
+		steps.Extend(functionTeardown(filepath.Join(path, "functions", fn)))
+	}
+
+	steps.Extend(stringsTeardown(filepath.Join(path, "strings")))
+
+	return steps.Append(Step{Rmdir, path, ""}), nil
+}
+
+// Unbind and remove a gadget by name, whatever it contains
+func ForceRemove(name string) error {
+	steps, err := TeardownSteps(filepath.Join(GadgetConfigBasePath, name))
+	if err != nil {
+		return err
+	}
+
+	return steps.Run()
+}
+
+// Remove a config, once its function links are gone
+func configTeardown(path string) (steps Steps) {
+	steps.Extend(stringsTeardown(filepath.Join(path, "strings")))
+	return steps.Append(Step{Rmdir, path, ""})
+}
+
+// Remove the language directories of a `strings` group, along with any
+// extra string directories within them
+func stringsTeardown(path string) (steps Steps) {
+	langs, _ := listDirs(path)
+
+	for _, lang := range langs {
+		steps.Extend(langTeardown(filepath.Join(path, lang)))
+	}
+
+	return
+}
+
+// Remove one language directory and its extra strings
+func langTeardown(path string) (steps Steps) {
+	var extras, _ = listDirs(path)
+
+	for _, extra := range extras {
+		steps.Append(Step{Rmdir, filepath.Join(path, extra), ""})
+	}
+
+	return steps.Append(Step{Rmdir, path, ""})
+}
+
+// Remove a function, and any groups created within it, deepest first.
+// Default groups, such as `lun.0` or `os_desc`, cannot be removed but go away
+// with the function. Links between groups within a function, as UVC uses,
+// are removed before any group.
+func functionTeardown(path string) (steps Steps) {
+	filepath.WalkDir(path, func(p string, ent os.DirEntry, err error) error {
+		if err == nil && ent.Type()&os.ModeSymlink != 0 {
+			steps.Append(Step{Remove, p, ""})
+		}
+		return nil
+	})
+
+	return steps.Extend(groupTeardown(path, Rmdir))
+}
+
+func groupTeardown(path string, action Action) (steps Steps) {
+	var subdirs, _ = listDirs(path)
+
+	for _, sub := range subdirs {
+		steps.Extend(groupTeardown(filepath.Join(path, sub), RmdirOptional))
+	}
+
+	return steps.Append(Step{action, path, ""})
+}
@@ -0,0 +1,156 @@
+package gadgetconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Run the steps, and should one fail, undo those that took effect, latest
+// first. The error includes both the failure and any problems rolling back.
+//
+// Removed links are restored, but removed directories are not, as configfs
+// would recreate them with default attributes; those removals are final.
+func (steps Steps) RunTransaction() error {
+	var tx transaction
+
+	for i := range steps {
+		var s = &steps[i]
+
+		if err := tx.run(*s); err != nil {
+			err = fmt.Errorf("step %d %+v: error %w", i, s, err)
+
+			if rerr := tx.rollback(); rerr != nil {
+				return errors.Join(err, fmt.Errorf("rollback: %w", rerr))
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transaction
+type transaction struct {
+	undo    Steps
+	created map[string]bool
+}
+
+// Run a step, recording how to reverse exactly what it did
+func (tx *transaction) run(s Step) error {
+	if tx.created == nil {
+		tx.created = make(map[string]bool)
+	}
+
+	switch s.Action {
+	case Mkdir, MkdirCreateOnly:
+		// Only the directories this step creates, which may be several
+		var missing []string
+		for dir := s.Arg0; ; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+				break
+			}
+			missing = append(missing, dir)
+		}
+
+		if err := s.Run(); err != nil {
+			return err
+		}
+
+		for i := len(missing) - 1; i >= 0; i-- {
+			tx.created[missing[i]] = true
+			tx.undo.Append(Step{Rmdir, missing[i], ""})
+		}
+
+	case Write, WriteBinary:
+		// Attributes of directories created here go away with them
+		var (
+			restore = !tx.within(s.Arg0) && s.Arg1 != ""
+			old     []byte
+		)
+
+		if restore {
+			old, _ = os.ReadFile(s.Arg0)
+		}
+
+		// An eject is undone by inserting the media again
+		var media string
+		if filepath.Base(s.Arg0) == "forced_eject" {
+			media = readAttr(filepath.Dir(s.Arg0), "file")
+		}
+
+		if err := s.Run(); err != nil {
+			return err
+		}
+
+		if restore && len(old) > 0 {
+			tx.undo.Append(Step{WriteBinary, s.Arg0, string(old)})
+		}
+
+		if media != "" {
+			tx.undo.Append(Step{Write, filepath.Join(filepath.Dir(s.Arg0), "file"), media})
+		}
+
+	case Remove:
+		// Links are restored to the same target, made absolute as configfs
+		// resolves it from the working directory
+		target, lerr := os.Readlink(s.Arg0)
+		if lerr == nil && !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(s.Arg0), target)
+		}
+
+		if err := s.Run(); err != nil {
+			return err
+		}
+
+		if lerr == nil {
+			tx.undo.Append(Step{Symlink, target, s.Arg0})
+		}
+
+	case Unbind:
+		var udc = readAttr(s.Arg0)
+
+		if err := s.Run(); err != nil {
+			return err
+		}
+
+		if udc != "" {
+			tx.undo.Append(Step{Bind, s.Arg0, udc})
+		}
+
+	default:
+		if err := s.Run(); err != nil {
+			return err
+		}
+
+		tx.undo.Append(s.Undo())
+	}
+
+	return nil
+}
+
+// Whether `path` is inside a directory created by this transaction
+func (tx *transaction) within(path string) bool {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if tx.created[dir] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Undo in reverse order, carrying on past failures
+func (tx *transaction) rollback() error {
+	var errs []error
+
+	for _, s := range tx.undo.Reverse() {
+		if err := s.Run(); err != nil {
+			errs = append(errs, fmt.Errorf("undo %+v: %w", s, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
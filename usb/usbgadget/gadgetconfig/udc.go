@@ -0,0 +1,71 @@
+package gadgetconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const UdcClassPath = "/sys/class/udc"
+
+// Udc describes a USB device controller and the state of its link
+type Udc struct {
+	Name         string
+	State        string // such as `configured` or `not attached`
+	CurrentSpeed string
+	MaximumSpeed string
+	Function     string // bound gadget driver, if any
+}
+
+func ReadUdc(name string) (*Udc, error) {
+	var dir = filepath.Join(UdcClassPath, name)
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	var read = func(attr string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, attr))
+		return strings.TrimSpace(string(data))
+	}
+
+	return &Udc{
+		Name:         name,
+		State:        read("state"),
+		CurrentSpeed: read("current_speed"),
+		MaximumSpeed: read("maximum_speed"),
+		Function:     read("function"),
+	}, nil
+}
+
+// Names of all gadgets defined in configfs
+func ListGadgets() []string {
+	entries, err := os.ReadDir(GadgetConfigBasePath)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, ent := range entries {
+		if ent.IsDir() {
+			names = append(names, ent.Name())
+		}
+	}
+
+	return names
+}
+
+// UDC the gadget is currently bound to, if any
+func (g *Gadget) BoundUdc() string {
+	udc, _ := g.ReadConfigfsFile("UDC")
+	return udc
+}
+
+// Detach the gadget from its UDC, which must happen before it can be removed
+func (g *Gadget) Unbind() error {
+	if g.BoundUdc() == "" {
+		return nil
+	}
+
+	return os.WriteFile(filepath.Join(g.gadgetPath(), "UDC"), []byte("\n"), 0664)
+}
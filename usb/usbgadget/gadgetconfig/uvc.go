@@ -0,0 +1,356 @@
+package gadgetconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// USB Video Class function. The formats, each with its frame sizes, are
+// groups within `streaming`, and are made known to the host by linking them
+// into a streaming header, which is in turn linked into the class
+// descriptors for each speed. The control interface has a header of its
+// own.
+//
+// Frames cannot change once their format is linked into the header, and the
+// header once linked into a class, so changes to the formats of a live
+// function need it removed and created again, as under a new name.
+//
+// See <https://docs.kernel.org/usb/gadget_uvc.html>
+
+// Streaming format types
+const (
+	UvcUncompressed = "uncompressed"
+	UvcMjpeg        = "mjpeg"
+)
+
+// Name of the control and streaming header groups
+const uvcHeader = "h"
+
+var (
+	uvcControlClasses   = []string{"fs", "ss"}
+	uvcStreamingClasses = []string{"fs", "hs", "ss"}
+
+	// Uncompressed format GUIDs are a FourCC followed by these bytes
+	uvcGuidSuffix = []byte{0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+)
+
+// Frame interval, in the 100 ns units UVC uses, for a frame rate
+func UvcInterval(fps int) int { return 10_000_000 / fps }
+
+// UvcFunction
+type UvcFunction struct {
+	Name string `json:"name"`
+
+	// Streaming endpoint, left at the kernel's defaults when zero
+	StreamingMaxPacket int `json:"streaming_maxpacket,omitempty"`
+	StreamingMaxBurst  int `json:"streaming_maxburst,omitempty"`
+	StreamingInterval  int `json:"streaming_interval,omitempty"`
+
+	// Interface string, in English only
+	FunctionName string `json:"function_name,omitempty"`
+
+	Formats []UvcFormat `json:"formats"`
+}
+
+// UvcFormat
+type UvcFormat struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Uncompressed only: pixel format as a FourCC, such as `YUY2` or `NV12`,
+	// and its bits per pixel
+	FourCC       string `json:"fourcc,omitempty"`
+	BitsPerPixel int    `json:"bits_per_pixel,omitempty"`
+
+	Frames []UvcFrame `json:"frames"`
+}
+
+// UvcFrame is one frame size of a format. Intervals are in 100 ns units,
+// fastest first, with the first as the default unless given. Bit rates and
+// buffer size are worked out from the size when zero, taking compressed
+// frames as no larger than 16 bits per pixel.
+type UvcFrame struct {
+	Name            string `json:"name"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	Intervals       []int  `json:"intervals"`
+	DefaultInterval int    `json:"default_interval,omitempty"`
+
+	MinBitRate         int `json:"min_bit_rate,omitempty"`
+	MaxBitRate         int `json:"max_bit_rate,omitempty"`
+	MaxFrameBufferSize int `json:"max_frame_buffer_size,omitempty"`
+}
+
+var _ Function = (*UvcFunction)(nil)
+
+func (fn *UvcFunction) GadgetFunctionName() string { return "uvc." + fn.Name }
+
+func (fn *UvcFunction) GadgetFunctionCreate() Steps {
+	var (
+		controlHeader   = "control/header/" + uvcHeader
+		streamingHeader = "streaming/header/" + uvcHeader
+		steps           = Steps{
+			Step{Write, "streaming_maxpacket", intAttr(fn.StreamingMaxPacket)},
+			Step{Write, "streaming_maxburst", intAttr(fn.StreamingMaxBurst)},
+			Step{Write, "streaming_interval", intAttr(fn.StreamingInterval)},
+			Step{Write, "function_name", fn.FunctionName},
+			Step{Mkdir, controlHeader, ""},
+		}
+	)
+
+	for _, class := range uvcControlClasses {
+		steps.Append(Step{Symlink, controlHeader, "control/class/" + class + "/" + uvcHeader})
+	}
+
+	for i := range fn.Formats {
+		steps.Extend(fn.Formats[i].steps())
+	}
+
+	// Formats into the header, then the header into each class
+	steps.Append(Step{Mkdir, streamingHeader, ""})
+
+	for i := range fn.Formats {
+		var f = &fn.Formats[i]
+		steps.Append(Step{Symlink, f.path(), streamingHeader + "/" + f.Name})
+	}
+
+	for _, class := range uvcStreamingClasses {
+		steps.Append(Step{Symlink, streamingHeader, "streaming/class/" + class + "/" + uvcHeader})
+	}
+
+	return steps
+}
+
+func (f *UvcFormat) path() string { return "streaming/" + f.Type + "/" + f.Name }
+
+func (f *UvcFormat) bitsPerPixel() int {
+	if f.Type == UvcUncompressed && f.BitsPerPixel > 0 {
+		return f.BitsPerPixel
+	}
+	return 16
+}
+
+func (f *UvcFormat) steps() Steps {
+	var steps = Steps{
+		Step{Mkdir, "", ""},
+	}
+
+	if f.Type == UvcUncompressed {
+		if f.FourCC != "" {
+			steps.Append(Step{WriteBinary, "guidFormat", string(append([]byte(f.FourCC), uvcGuidSuffix...))})
+		}
+		steps.Append(Step{Write, "bBitsPerPixel", intAttr(f.BitsPerPixel)})
+	}
+
+	for i := range f.Frames {
+		var (
+			fr     = &f.Frames[i]
+			attrs  = fr.attrs(f.bitsPerPixel())
+			fsteps = Steps{
+				Step{Mkdir, "", ""},
+				Step{Write, "wWidth", strconv.Itoa(fr.Width)},
+				Step{Write, "wHeight", strconv.Itoa(fr.Height)},
+				Step{Write, "dwMinBitRate", strconv.FormatUint(attrs.minBitRate, 10)},
+				Step{Write, "dwMaxBitRate", strconv.FormatUint(attrs.maxBitRate, 10)},
+				Step{Write, "dwMaxVideoFrameBufferSize", strconv.FormatUint(attrs.bufSize, 10)},
+				Step{Write, "dwFrameInterval", joinInts(fr.Intervals, "\n")},
+				Step{Write, "dwDefaultFrameInterval", strconv.Itoa(attrs.defaultInterval)},
+			}
+		)
+
+		steps.Extend(fsteps.PrependPath(fr.Name))
+	}
+
+	return steps.PrependPath(f.path())
+}
+
+// Frame attributes with the defaults worked out. Bit rates are in 64 bits,
+// as they overflow a 32 bit int for all but the smallest frames, and
+// unsigned, as the largest frames overflow an int64 along the way.
+type uvcFrameAttrs struct {
+	defaultInterval int
+	minBitRate      uint64
+	maxBitRate      uint64
+	bufSize         uint64
+}
+
+func (fr *UvcFrame) attrs(bpp int) uvcFrameAttrs {
+	var a = uvcFrameAttrs{
+		defaultInterval: fr.DefaultInterval,
+		minBitRate:      uint64(fr.MinBitRate),
+		maxBitRate:      uint64(fr.MaxBitRate),
+		bufSize:         uint64(fr.MaxFrameBufferSize),
+	}
+
+	if len(fr.Intervals) == 0 {
+		return a
+	}
+
+	var (
+		fastest = fr.Intervals[0]
+		slowest = fr.Intervals[0]
+		size    = uint64(fr.Width) * uint64(fr.Height) * uint64(bpp) / 8
+	)
+
+	for _, v := range fr.Intervals {
+		fastest = min(fastest, v)
+		slowest = max(slowest, v)
+	}
+
+	if a.defaultInterval == 0 {
+		a.defaultInterval = fr.Intervals[0]
+	}
+
+	if a.bufSize == 0 {
+		a.bufSize = size
+	}
+
+	if a.minBitRate == 0 && slowest > 0 {
+		a.minBitRate = size * 8 * 10_000_000 / uint64(slowest)
+	}
+
+	if a.maxBitRate == 0 && fastest > 0 {
+		a.maxBitRate = size * 8 * 10_000_000 / uint64(fastest)
+	}
+
+	return a
+}
+
+func joinInts(vs []int, sep string) string {
+	var strs = make([]string, len(vs))
+	for i, v := range vs {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, sep)
+}
+
+// Formats linked into the streaming header, by name, as configfs does not
+// keep the order they were linked in
+func loadUvcFormats(path string) (formats []UvcFormat) {
+	var (
+		header   = filepath.Join(path, "streaming", "header", uvcHeader)
+		links, _ = listLinks(header)
+	)
+
+	for _, link := range links {
+		var f = UvcFormat{Name: filepath.Base(link)}
+
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+
+		f.Type = filepath.Base(filepath.Dir(target))
+
+		var formatPath = filepath.Join(path, "streaming", f.Type, f.Name)
+
+		if f.Type == UvcUncompressed {
+			if guid := readBinaryAttr(formatPath, "guidFormat"); len(guid) == 16 && bytes.Equal(guid[4:], uvcGuidSuffix) {
+				f.FourCC = string(guid[:4])
+			}
+			f.BitsPerPixel = readIntAttr(formatPath, "bBitsPerPixel")
+		}
+
+		frames, _ := listDirs(formatPath)
+		for _, name := range frames {
+			var (
+				framePath = filepath.Join(formatPath, name)
+				fr        = UvcFrame{
+					Name:               name,
+					Width:              readIntAttr(framePath, "wWidth"),
+					Height:             readIntAttr(framePath, "wHeight"),
+					DefaultInterval:    readIntAttr(framePath, "dwDefaultFrameInterval"),
+					MinBitRate:         readIntAttr(framePath, "dwMinBitRate"),
+					MaxBitRate:         readIntAttr(framePath, "dwMaxBitRate"),
+					MaxFrameBufferSize: readIntAttr(framePath, "dwMaxVideoFrameBufferSize"),
+				}
+			)
+
+			for _, v := range strings.Fields(readAttr(framePath, "dwFrameInterval")) {
+				if n, err := strconv.Atoi(v); err == nil {
+					fr.Intervals = append(fr.Intervals, n)
+				}
+			}
+
+			f.Frames = append(f.Frames, fr)
+		}
+
+		formats = append(formats, f)
+	}
+
+	return
+}
+
+func (fn *UvcFunction) Validate() error {
+	var (
+		errs  []error
+		names = make(map[string]bool)
+	)
+
+	var check = func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(len(fn.Formats) > 0, "no formats")
+
+	for i := range fn.Formats {
+		var f = &fn.Formats[i]
+
+		check(validGroupName(f.Name) && f.Name != uvcHeader, "bad format name `%s`", f.Name)
+		check(!names[f.Name], "duplicate format `%s`", f.Name)
+		names[f.Name] = true
+
+		switch f.Type {
+		case UvcUncompressed:
+			check(len(f.FourCC) == 0 || len(f.FourCC) == 4, "format `%s` FourCC `%s` must be 4 characters", f.Name, f.FourCC)
+			check(f.BitsPerPixel >= 0 && f.BitsPerPixel <= 0xff, "format `%s` bits per pixel %d out of range", f.Name, f.BitsPerPixel)
+
+		case UvcMjpeg:
+			check(f.FourCC == "" && f.BitsPerPixel == 0, "format `%s` FourCC and bits per pixel are for uncompressed formats", f.Name)
+
+		default:
+			check(false, "format `%s` type `%s` must be `%s` or `%s`", f.Name, f.Type, UvcUncompressed, UvcMjpeg)
+		}
+
+		check(len(f.Frames) > 0, "format `%s` has no frames", f.Name)
+
+		var frames = make(map[string]bool)
+
+		for _, fr := range f.Frames {
+			check(validGroupName(fr.Name), "format `%s` bad frame name `%s`", f.Name, fr.Name)
+			check(!frames[fr.Name], "format `%s` duplicate frame `%s`", f.Name, fr.Name)
+			frames[fr.Name] = true
+
+			check(fr.Width > 0 && fr.Width <= 0xffff && fr.Height > 0 && fr.Height <= 0xffff,
+				"frame `%s` size %dx%d out of range", fr.Name, fr.Width, fr.Height)
+			check(len(fr.Intervals) > 0, "frame `%s` has no intervals", fr.Name)
+
+			var hasDefault = fr.DefaultInterval == 0
+			for _, v := range fr.Intervals {
+				check(v > 0, "frame `%s` interval %d out of range", fr.Name, v)
+				hasDefault = hasDefault || v == fr.DefaultInterval
+			}
+
+			check(hasDefault, "frame `%s` default interval %d is not one of its intervals", fr.Name, fr.DefaultInterval)
+
+			// Written as 32 bit values, which the kernel rejects partway
+			// through creation if out of range
+			var a = fr.attrs(f.bitsPerPixel())
+			check(a.minBitRate <= math.MaxUint32 && a.maxBitRate <= math.MaxUint32,
+				"frame `%s` bit rate %d to %d does not fit in 32 bits", fr.Name, a.minBitRate, a.maxBitRate)
+			check(a.bufSize <= math.MaxUint32, "frame `%s` buffer size %d does not fit in 32 bits", fr.Name, a.bufSize)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validGroupName(name string) bool { return name != "" && !strings.ContainsRune(name, '/') }
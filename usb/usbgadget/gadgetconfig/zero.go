@@ -0,0 +1,129 @@
+package gadgetconfig
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The Gadget Zero test functions, for exercising a UDC driver from the host
+// with usbtest or the usbdevfs package. SourceSink sends an endless stream
+// of data on its IN endpoints and accepts anything on its OUT endpoints, and
+// Loopback sends back whatever it receives.
+//
+// See <https://docs.kernel.org/usb/gadget-testing.html>
+
+// Data SourceSink sends, and checks it receives. With mod63, each byte is its
+// offset within the packet, modulo 63.
+const (
+	SourceSinkZeros = "zeros"
+	SourceSinkMod63 = "mod63"
+	SourceSinkNone  = "none"
+)
+
+var sourceSinkPatterns = []string{SourceSinkZeros, SourceSinkMod63, SourceSinkNone}
+
+// Limits of the isochronous endpoint attributes
+const (
+	isocMaxInterval = 16
+	isocMaxPacket   = 1024
+	isocMaxMult     = 2
+	isocMaxMaxBurst = 15
+)
+
+// LoopbackFunction
+type LoopbackFunction struct {
+	Name string `json:"name"`
+
+	// Requests queued and their size, left at the kernel's defaults when zero
+	QLen       int `json:"qlen,omitempty"`
+	BulkBufLen int `json:"bulk_buflen,omitempty"`
+}
+
+var _ Function = (*LoopbackFunction)(nil)
+
+func (fn *LoopbackFunction) GadgetFunctionName() string { return "Loopback." + fn.Name }
+
+func (fn *LoopbackFunction) GadgetFunctionCreate() Steps {
+	return Steps{
+		Step{Write, "qlen", intAttr(fn.QLen)},
+		Step{Write, "bulk_buflen", intAttr(fn.BulkBufLen)},
+	}
+}
+
+func (fn *LoopbackFunction) Validate() error {
+	if fn.QLen < 0 || fn.BulkBufLen < 0 {
+		return fmt.Errorf("qlen %d or bulk_buflen %d out of range", fn.QLen, fn.BulkBufLen)
+	}
+	return nil
+}
+
+// SourceSinkFunction has bulk endpoints in its default altsetting, and adds
+// isochronous endpoints in altsetting 1. Fields left at zero keep the
+// kernel's defaults.
+type SourceSinkFunction struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern,omitempty"`
+
+	// Isochronous endpoints: bInterval exponent, packet size, extra
+	// transactions per microframe at high speed, and burst at super speed
+	IsocInterval  int `json:"isoc_interval,omitempty"`
+	IsocMaxPacket int `json:"isoc_maxpacket,omitempty"`
+	IsocMult      int `json:"isoc_mult,omitempty"`
+	IsocMaxBurst  int `json:"isoc_maxburst,omitempty"`
+
+	// Request size and requests queued for the bulk endpoints, and requests
+	// queued for the isochronous ones
+	BulkBufLen int `json:"bulk_buflen,omitempty"`
+	BulkQLen   int `json:"bulk_qlen,omitempty"`
+	IsoQLen    int `json:"iso_qlen,omitempty"`
+}
+
+var _ Function = (*SourceSinkFunction)(nil)
+
+func (fn *SourceSinkFunction) GadgetFunctionName() string { return "SourceSink." + fn.Name }
+
+func (fn *SourceSinkFunction) GadgetFunctionCreate() Steps {
+	var pattern string
+	for i, v := range sourceSinkPatterns {
+		if v == fn.Pattern {
+			pattern = strconv.Itoa(i)
+		}
+	}
+
+	return Steps{
+		Step{Write, "pattern", pattern},
+		Step{Write, "isoc_interval", intAttr(fn.IsocInterval)},
+		Step{Write, "isoc_maxpacket", intAttr(fn.IsocMaxPacket)},
+		Step{Write, "isoc_mult", intAttr(fn.IsocMult)},
+		Step{Write, "isoc_maxburst", intAttr(fn.IsocMaxBurst)},
+		Step{Write, "bulk_buflen", intAttr(fn.BulkBufLen)},
+		Step{Write, "bulk_qlen", intAttr(fn.BulkQLen)},
+		Step{Write, "iso_qlen", intAttr(fn.IsoQLen)},
+	}
+}
+
+func (fn *SourceSinkFunction) Validate() error {
+	var errs []error
+
+	var check = func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	switch fn.Pattern {
+	case "", SourceSinkZeros, SourceSinkMod63, SourceSinkNone:
+	default:
+		check(false, "pattern `%s` must be `%s`, `%s` or `%s`", fn.Pattern, SourceSinkZeros, SourceSinkMod63, SourceSinkNone)
+	}
+
+	check(fn.IsocInterval >= 0 && fn.IsocInterval <= isocMaxInterval, "isoc_interval %d must be 1 to %d", fn.IsocInterval, isocMaxInterval)
+	check(fn.IsocMaxPacket >= 0 && fn.IsocMaxPacket <= isocMaxPacket, "isoc_maxpacket %d must be up to %d", fn.IsocMaxPacket, isocMaxPacket)
+	check(fn.IsocMult >= 0 && fn.IsocMult <= isocMaxMult, "isoc_mult %d must be up to %d", fn.IsocMult, isocMaxMult)
+	check(fn.IsocMaxBurst >= 0 && fn.IsocMaxBurst <= isocMaxMaxBurst, "isoc_maxburst %d must be up to %d", fn.IsocMaxBurst, isocMaxMaxBurst)
+	check(fn.BulkBufLen >= 0 && fn.BulkQLen >= 0 && fn.IsoQLen >= 0,
+		"bulk_buflen %d, bulk_qlen %d or iso_qlen %d out of range", fn.BulkBufLen, fn.BulkQLen, fn.IsoQLen)
+
+	return errors.Join(errs...)
+}
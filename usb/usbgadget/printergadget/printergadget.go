@@ -0,0 +1,106 @@
+package printergadget
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.pdmccormick.com/linuxuapi/internal/ioctl"
+)
+
+// Print jobs from the host are read from the device, and any response, such
+// as PJL status, is written back to it. The status reported to the host's
+// GET_PORT_STATUS request is set with SetStatus.
+//
+// See <https://docs.kernel.org/usb/gadget_printer.html>
+
+const (
+	DefaultDevice = "/dev/g_printer0"
+
+	// Devices of the printer functions, by name
+	ClassPath = "/sys/class/usb_printer_gadget"
+)
+
+const (
+	_GADGET_GET_PRINTER_STATUS = 0x80016721
+	_GADGET_SET_PRINTER_STATUS = 0xc0016722
+)
+
+// Status bits, as in the IEEE 1284 port status
+type Status byte
+
+const (
+	StatusNotError   Status = 0x08
+	StatusSelected   Status = 0x10
+	StatusPaperEmpty Status = 0x20
+)
+
+// Device
+type Device struct {
+	f *os.File
+}
+
+// Printer function device nodes, such as `/dev/g_printer0`
+func FindDevices() []string {
+	entries, err := os.ReadDir(ClassPath)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, ent := range entries {
+		names = append(names, filepath.Join("/dev", ent.Name()))
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func OpenDevice(name string) (*Device, error) {
+	if name == "" {
+		name = DefaultDevice
+	}
+
+	if !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "./") {
+		name = filepath.Join("/dev", name)
+	}
+
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Device{f: f}, nil
+}
+
+func (dev *Device) Close() error { return dev.f.Close() }
+
+// Job data from the host
+func (dev *Device) Read(p []byte) (int, error) { return dev.f.Read(p) }
+
+// Data for the host to read back
+func (dev *Device) Write(p []byte) (int, error) { return dev.f.Write(p) }
+
+func (dev *Device) SetReadDeadline(t time.Time) error { return dev.f.SetReadDeadline(t) }
+
+// The kernel returns the status as the result of the ioctl, and takes it as
+// the argument rather than through a pointer
+func (dev *Device) ioctl(req, arg uintptr) (uintptr, error) {
+	return ioctl.Value(dev.f, req, arg)
+}
+
+func (dev *Device) Status() (Status, error) {
+	r, err := dev.ioctl(_GADGET_GET_PRINTER_STATUS, 0)
+	return Status(r), err
+}
+
+func (dev *Device) SetStatus(s Status) error {
+	_, err := dev.ioctl(_GADGET_SET_PRINTER_STATUS, uintptr(s))
+	return err
+}
+
+func (s Status) Ok() bool {
+	return s&StatusNotError != 0 && s&StatusSelected != 0 && s&StatusPaperEmpty == 0
+}